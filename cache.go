@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Gets the file where the buffered events are saved. Tests replace it to not share the app folder
+var eventsCachePath = func() string {
+	return filepath.Join(dailyApp.Storage().RootURI().Path(), "events-cache.json")
+}
+
+// The buffered events as saved to disk so that they can be shown as soon as the app starts
+type eventsCache struct {
+	Start  time.Time     `json:"start"`
+	End    time.Time     `json:"end"`
+	Events []cachedEvent `json:"events"`
+}
+
+// An event with its fields exported to be encoded
+type cachedEvent struct {
+	Id          string             `json:"id"`
+	Title       string             `json:"title"`
+	Start       time.Time          `json:"start"`
+	End         time.Time          `json:"end"`
+	Location    string             `json:"location,omitempty"`
+	Details     string             `json:"details,omitempty"`
+	Notifiable  bool               `json:"notifiable,omitempty"`
+	Response    responseStatus     `json:"response,omitempty"`
+	Attachments []cachedAttachment `json:"attachments,omitempty"`
+	HtmlLink    string             `json:"htmlLink,omitempty"`
+	Updated     time.Time          `json:"updated"`
+	Source      string             `json:"source,omitempty"`
+	Kind        sourceKind         `json:"kind,omitempty"`
+	CalendarId  string             `json:"calendarId,omitempty"`
+	Organizer   bool               `json:"organizer,omitempty"`
+	Recurring   bool               `json:"recurring,omitempty"`
+	Free        bool               `json:"free,omitempty"`
+	Tags        []string           `json:"tags,omitempty"`
+	DialIn      cachedDialIn       `json:"dialIn"`
+	Reminders   []int              `json:"reminders,omitempty"`
+}
+
+type cachedAttachment struct {
+	Title string `json:"title"`
+	Url   string `json:"url"`
+}
+
+type cachedDialIn struct {
+	Url   string `json:"url,omitempty"`
+	Phone string `json:"phone,omitempty"`
+	Pin   string `json:"pin,omitempty"`
+}
+
+// Saves the events retrieved between start and end, replacing the previous ones
+func saveEventsCache(start time.Time, end time.Time, events []event) {
+	cache := eventsCache{Start: start, End: end}
+	for _, event := range events {
+		cached := cachedEvent{
+			Id:         event.id,
+			Title:      event.title,
+			Start:      event.start,
+			End:        event.end,
+			Location:   event.location,
+			Details:    event.details,
+			Notifiable: event.notifiable,
+			Response:   event.response,
+			HtmlLink:   event.htmlLink,
+			Updated:    event.updated,
+			Source:     event.source,
+			Kind:       event.kind,
+			CalendarId: event.calendarId,
+			Organizer:  event.organizer,
+			Recurring:  event.recurring,
+			Free:       event.free,
+			Tags:       event.tags,
+			DialIn:     cachedDialIn{Url: event.dialIn.url, Phone: event.dialIn.phone, Pin: event.dialIn.pin},
+			Reminders:  event.reminders,
+		}
+		for _, attachment := range event.attachments {
+			cached.Attachments = append(cached.Attachments, cachedAttachment{Title: attachment.title, Url: attachment.url})
+		}
+		cache.Events = append(cache.Events, cached)
+	}
+
+	content, err := json.Marshal(cache)
+	if err != nil {
+		slog.Warn("Could not encode events cache", "error", err)
+		return
+	}
+	// the file is replaced in one go so that a crash never leaves it half written
+	path := eventsCachePath()
+	err = os.MkdirAll(filepath.Dir(path), 0700)
+	if err == nil {
+		err = os.WriteFile(path+".tmp", content, 0600)
+	}
+	if err == nil {
+		err = os.Rename(path+".tmp", path)
+	}
+	if err != nil {
+		slog.Warn("Could not save events cache", "error", err)
+	}
+}
+
+// Loads the events saved by the last retrieval, with the range they were retrieved for. A missing or invalid cache
+// returns an error
+func loadEventsCache() (time.Time, time.Time, []event, error) {
+	content, err := os.ReadFile(eventsCachePath())
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, err
+	}
+	var cache eventsCache
+	err = json.Unmarshal(content, &cache)
+	if err != nil {
+		return time.Time{}, time.Time{}, nil, err
+	}
+
+	var events []event
+	for _, cached := range cache.Events {
+		loaded := event{
+			id:         cached.Id,
+			title:      cached.Title,
+			start:      cached.Start,
+			end:        cached.End,
+			location:   cached.Location,
+			details:    cached.Details,
+			notifiable: cached.Notifiable,
+			response:   cached.Response,
+			htmlLink:   cached.HtmlLink,
+			updated:    cached.Updated,
+			source:     cached.Source,
+			kind:       cached.Kind,
+			calendarId: cached.CalendarId,
+			organizer:  cached.Organizer,
+			recurring:  cached.Recurring,
+			free:       cached.Free,
+			tags:       cached.Tags,
+			dialIn:     dialIn{url: cached.DialIn.Url, phone: cached.DialIn.Phone, pin: cached.DialIn.Pin},
+			reminders:  cached.Reminders,
+		}
+		for _, cachedAttachment := range cached.Attachments {
+			loaded.attachments = append(loaded.attachments, attachment{title: cachedAttachment.Title, url: cachedAttachment.Url})
+		}
+		events = append(events, loaded)
+	}
+
+	return cache.Start, cache.End, events, nil
+}
+
+// Deletes the saved events, for example when the calendar changes
+func clearEventsCache() {
+	err := os.Remove(eventsCachePath())
+	if err != nil && !os.IsNotExist(err) {
+		slog.Warn("Could not delete events cache", "error", err)
+	}
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+	"google.golang.org/api/calendar/v3"
+)
+
+func TestEventsCacheRoundTrip(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	cachePath := filepath.Join(t.TempDir(), "daily", "events-cache.json")
+	originalCachePath := eventsCachePath
+	eventsCachePath = func() string { return cachePath }
+	defer func() { eventsCachePath = originalCachePath }()
+
+	start := time.Date(2024, 11, 13, 0, 0, 0, 0, time.UTC)
+	events := []event{
+		{
+			id: "review", title: "Design review", start: start.Add(50 * time.Hour), end: start.Add(51 * time.Hour),
+			location: "https://meet.google.com/abc-defg-hij", details: "Agenda #design", notifiable: true, response: tentative,
+			attachments: []attachment{{title: "Agenda", url: "https://docs.google.com/document/d/1"}, {title: "Slides", url: "https://docs.google.com/presentation/d/2"}},
+			htmlLink:    "https://calendar.google.com/event?eid=1", updated: start, source: "Work", kind: googleSource, calendarId: "primary",
+			recurring: true, tags: []string{"design"}, dialIn: dialIn{url: "https://meet.google.com/abc-defg-hij", phone: "+1 555-0100", pin: "1234"},
+			reminders: []int{15, 5},
+		},
+		{id: "focus", title: "Focus", start: start.Add(60 * time.Hour), end: start.Add(62 * time.Hour), updated: start, organizer: true, free: true},
+	}
+	saveEventsCache(start, start.AddDate(0, 0, 10), events)
+
+	actualStart, actualEnd, actual, err := loadEventsCache()
+	if err != nil {
+		t.Fatal("Error loading cache", err)
+	}
+	if !actualStart.Equal(start) || !actualEnd.Equal(start.AddDate(0, 0, 10)) {
+		t.Errorf("Actual range %v - %v doesn't match expected", actualStart, actualEnd)
+	}
+	if !reflect.DeepEqual(actual, events) {
+		t.Errorf("Actual %+v doesn't match expected %+v", actual, events)
+	}
+
+	clearEventsCache()
+	if _, _, _, err := loadEventsCache(); err == nil {
+		t.Errorf("Cache was not cleared")
+	}
+}
+
+func TestGoogleCalendarStartsWithCachedEvents(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("calendar-id", "primary")
+	dailyApp.Preferences().SetString("calendar-token", `{"access_token": "fake"}`)
+
+	day := time.Date(2024, 11, 18, 0, 0, 0, 0, time.Local)
+	server := startFakeCalendarServer(t, []*calendar.Event{createCalendarEvent("standup", day.Add(9*time.Hour), day)})
+	saveEventsCache(day.AddDate(0, 0, -5), day.AddDate(0, 0, 5), []event{{id: "cached", title: "cached", start: day.Add(10 * time.Hour), end: day.Add(11 * time.Hour), calendarId: "primary"}})
+
+	source, err := newGoogleCalendarEventSource()
+	if err != nil {
+		t.Fatal("Error creating event source", err)
+	}
+	events, refreshed, err := source.getEvents(day, false)
+	if err != nil || refreshed || len(events) != 1 || events[0].id != "cached" || len(server.requests) != 0 {
+		t.Errorf("Actual events %v (refreshed = %t, error %v) are not the cached ones", events, refreshed, err)
+	}
+
+	events, _, err = source.getEvents(day, true)
+	if err != nil || len(events) != 1 || events[0].id != "standup" {
+		t.Fatalf("Actual events %v (error %v) were not retrieved", events, err)
+	}
+	_, _, cached, _ := loadEventsCache()
+	if len(cached) != 1 || cached[0].id != "standup" {
+		t.Errorf("Actual cached events %v were not replaced by the retrieved ones", cached)
+	}
+}
+
+func TestResetEventSourceOnlyWhenSourceChanged(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	cachePath := filepath.Join(t.TempDir(), "events-cache.json")
+	originalCachePath := eventsCachePath
+	eventsCachePath = func() string { return cachePath }
+	defer func() { eventsCachePath = originalCachePath }()
+	defer func() { eventSource = nil }()
+
+	dailyApp.Preferences().SetString("ics-url", "https://example.com/school.ics")
+	start := time.Date(2024, 11, 13, 0, 0, 0, 0, time.UTC)
+	saveEventsCache(start, start.AddDate(0, 0, 10), nil)
+	eventSource = newDummyEventSource()
+
+	previous := sourcePreferences()
+	dailyApp.Preferences().SetString("accent-color", "#ff0000")
+	resetEventSource(previous)
+	if _, _, _, err := loadEventsCache(); eventSource == nil || err != nil {
+		t.Errorf("Actual source %v or cache error %v were reset without changing the source", eventSource, err)
+	}
+
+	previous = sourcePreferences()
+	dailyApp.Preferences().SetString("ics-url", "https://example.com/work.ics")
+	resetEventSource(previous)
+	if _, _, _, err := loadEventsCache(); eventSource != nil || err == nil {
+		t.Errorf("Actual source %v or cache were not reset after changing the feed", eventSource)
+	}
+}
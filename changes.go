@@ -0,0 +1,156 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// How far ahead changes to events are tracked. Events further away can enter or leave the buffer when it moves, which
+// would look like they were added or removed
+const changesHorizon = 3 * 24 * time.Hour
+
+type changeKind string
+
+const (
+	added   changeKind = "added"
+	removed changeKind = "removed"
+	moved   changeKind = "moved"
+)
+
+// A change to an event picked up by a refresh
+type eventChange struct {
+	kind  changeKind
+	event event
+}
+
+var (
+	// The upcoming events at the last refresh, by id, and the window they start in
+	knownEvents        map[string]event
+	knownFrom, knownTo time.Time
+	// Changes picked up while the main window was hidden, to show when it is shown again
+	pendingChanges   []eventChange
+	mainWindowHidden bool
+	changesBanner    *fyne.Container
+)
+
+// Compares the buffered events with the ones of the previous refresh. The changes are kept to show them later if the
+// main window is hidden
+func trackChanges(events []event) {
+	from := now()
+	to := from.Add(changesHorizon)
+	current := make(map[string]event)
+	for _, event := range events {
+		if !event.start.Before(from) && event.start.Before(to) {
+			current[event.id] = event
+		}
+	}
+
+	if knownEvents != nil && (mainWindow == nil || mainWindowHidden) {
+		// the window moved since the last refresh, so only the events that start in both windows can be compared
+		overlapFrom, overlapTo := from, to
+		if knownFrom.After(overlapFrom) {
+			overlapFrom = knownFrom
+		}
+		if knownTo.Before(overlapTo) {
+			overlapTo = knownTo
+		}
+		changes := findChanges(startingBetween(knownEvents, overlapFrom, overlapTo), startingBetween(current, overlapFrom, overlapTo), overlapFrom)
+		if len(changes) > 0 {
+			slog.Debug(fmt.Sprintf("Detected %d event change(s) while the window is hidden", len(changes)))
+			pendingChanges = mergeChanges(pendingChanges, changes)
+		}
+	}
+	knownEvents, knownFrom, knownTo = current, from, to
+}
+
+// Gets the events that start in the range, by id
+func startingBetween(events map[string]event, from time.Time, to time.Time) map[string]event {
+	result := make(map[string]event)
+	for id, event := range events {
+		if !event.start.Before(from) && event.start.Before(to) {
+			result[id] = event
+		}
+	}
+
+	return result
+}
+
+// Gets the events that were added, removed or moved between the two sets of events. Events in the past are not
+// considered removed since they are just no longer upcoming
+func findChanges(previous map[string]event, current map[string]event, from time.Time) []eventChange {
+	var result []eventChange
+	for id, event := range current {
+		previousEvent, found := previous[id]
+		if !found {
+			result = append(result, eventChange{kind: added, event: event})
+		} else if !previousEvent.start.Equal(event.start) || !previousEvent.end.Equal(event.end) {
+			result = append(result, eventChange{kind: moved, event: event})
+		}
+	}
+	for id, event := range previous {
+		if _, found := current[id]; !found && !event.start.Before(from) {
+			result = append(result, eventChange{kind: removed, event: event})
+		}
+	}
+	slices.SortFunc(result, func(one eventChange, other eventChange) int { return one.event.start.Compare(other.event.start) })
+
+	return result
+}
+
+// Adds the new changes to the pending ones, keeping only the latest change of each event
+func mergeChanges(pending []eventChange, changes []eventChange) []eventChange {
+	var result []eventChange
+	for _, change := range pending {
+		if !slices.ContainsFunc(changes, func(other eventChange) bool { return other.event.id == change.event.id }) {
+			result = append(result, change)
+		}
+	}
+	for _, change := range changes {
+		pos := slices.IndexFunc(pending, func(other eventChange) bool { return other.event.id == change.event.id })
+		if pos >= 0 && pending[pos].kind == added {
+			if change.kind == removed {
+				// it came and went without being seen
+				continue
+			}
+			change.kind = added
+		}
+		result = append(result, change)
+	}
+	slices.SortStableFunc(result, func(one eventChange, other eventChange) int { return one.event.start.Compare(other.event.start) })
+
+	return result
+}
+
+// Shows a dismissible banner with the changes picked up while the main window was hidden, if any
+func showPendingChanges() {
+	if changesBanner == nil || len(pendingChanges) == 0 {
+		return
+	}
+
+	var lines []string
+	for _, change := range pendingChanges {
+		lines = append(lines, message("change-"+string(change.kind))+change.event.start.Format("Mon 3:04PM ")+change.event.title)
+	}
+	summary := widget.NewLabelWithStyle(fmt.Sprintf(message("events-changed"), len(pendingChanges)), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	dismissButton := widget.NewButtonWithIcon("", theme.CancelIcon(), func() { changesBanner.Hide() })
+	dismissButton.Importance = widget.LowImportance
+	details := widget.NewLabel(strings.Join(lines, "\n"))
+	details.Wrapping = fyne.TextWrapWord
+
+	changesBanner.Objects = []fyne.CanvasObject{
+		container.NewBorder(nil, nil, nil, dismissButton, summary),
+		details,
+		widget.NewSeparator(),
+	}
+	changesBanner.Show()
+	changesBanner.Refresh()
+	pendingChanges = nil
+}
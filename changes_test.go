@@ -0,0 +1,100 @@
+package main
+
+import (
+	"slices"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestFindChanges(t *testing.T) {
+	current := now().Truncate(time.Minute)
+	unchanged := event{id: "unchanged", start: current.Add(time.Hour), end: current.Add(2 * time.Hour)}
+	original := event{id: "moved", start: current.Add(3 * time.Hour), end: current.Add(4 * time.Hour)}
+	rescheduled := original
+	rescheduled.start, rescheduled.end = original.start.Add(time.Hour), original.end.Add(time.Hour)
+	cancelled := event{id: "cancelled", start: current.Add(5 * time.Hour), end: current.Add(6 * time.Hour)}
+	finished := event{id: "finished", start: current.Add(-time.Hour), end: current}
+	newEvent := event{id: "new", start: current.Add(30 * time.Minute), end: current.Add(time.Hour)}
+
+	previous := map[string]event{"unchanged": unchanged, "moved": original, "cancelled": cancelled, "finished": finished}
+	latest := map[string]event{"unchanged": unchanged, "moved": rescheduled, "new": newEvent}
+	actual := findChanges(previous, latest, current)
+
+	var actualChanges []string
+	for _, change := range actual {
+		actualChanges = append(actualChanges, string(change.kind)+" "+change.event.id)
+	}
+	expectedChanges := []string{"added new", "moved moved", "removed cancelled"}
+	if !slices.Equal(actualChanges, expectedChanges) {
+		t.Errorf("Actual %q don't match expected %q", actualChanges, expectedChanges)
+	}
+}
+
+func TestMergeChanges(t *testing.T) {
+	start := time.Date(2024, 11, 18, 9, 0, 0, 0, time.Local)
+	first := event{id: "first", start: start}
+	second := event{id: "second", start: start.Add(time.Hour)}
+	third := event{id: "third", start: start.Add(2 * time.Hour)}
+	pending := []eventChange{{kind: added, event: first}, {kind: moved, event: second}}
+	changes := []eventChange{{kind: moved, event: first}, {kind: removed, event: second}, {kind: added, event: third}}
+
+	actual := mergeChanges(pending, changes)
+	var actualChanges []string
+	for _, change := range actual {
+		actualChanges = append(actualChanges, string(change.kind)+" "+change.event.id)
+	}
+	expectedChanges := []string{"added first", "removed second", "added third"}
+	if !slices.Equal(actualChanges, expectedChanges) {
+		t.Errorf("Actual %q don't match expected %q", actualChanges, expectedChanges)
+	}
+
+	actual = mergeChanges(actual, []eventChange{{kind: removed, event: third}})
+	if len(actual) != 2 || actual[1].event.id != "second" {
+		t.Errorf("Actual %v still has an event that was added and removed while hidden", actual)
+	}
+}
+
+func TestTrackChangesOnlyWhileHidden(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	mainWindow = dailyApp.NewWindow("test")
+	defer func() { mainWindow, knownEvents, pendingChanges, mainWindowHidden = nil, nil, nil, false }()
+
+	start := now().Add(time.Hour)
+	meeting := event{id: "meeting", start: start, end: start.Add(time.Hour)}
+	review := event{id: "review", start: start.Add(2 * time.Hour), end: start.Add(3 * time.Hour)}
+	trackChanges([]event{meeting})
+	trackChanges([]event{meeting, review})
+	if len(pendingChanges) != 0 {
+		t.Errorf("Actual %v changes were tracked while the window is shown", pendingChanges)
+	}
+
+	mainWindowHidden = true
+	trackChanges([]event{review})
+	if len(pendingChanges) != 1 || pendingChanges[0].kind != removed || pendingChanges[0].event.id != "meeting" {
+		t.Errorf("Actual %v changes don't match the expected removal", pendingChanges)
+	}
+}
+
+func TestTrackChangesOnlyComparesBothWindows(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	defer func(original func() time.Time) { now = original }(now)
+	defer func() { knownEvents, pendingChanges, mainWindowHidden = nil, nil, false }()
+	mainWindowHidden = true
+
+	currentTime := time.Date(2024, 11, 18, 9, 0, 0, 0, time.Local)
+	now = func() time.Time { return currentTime }
+	finished := event{id: "finished", start: currentTime.Add(30 * time.Minute), end: currentTime.Add(time.Hour)}
+	trackChanges([]event{finished})
+
+	// the event further away was already there but only entered the window now
+	currentTime = currentTime.Add(2 * time.Hour)
+	later := event{id: "later", start: currentTime.Add(changesHorizon - time.Hour), end: currentTime.Add(changesHorizon)}
+	trackChanges([]event{later})
+	if len(pendingChanges) != 0 {
+		t.Errorf("Actual %v changes were tracked for events outside of one of the windows", pendingChanges)
+	}
+}
@@ -4,11 +4,19 @@ import (
 	"errors"
 	"flag"
 	"fmt"
+	"html"
+	"image/color"
 	"log/slog"
+	"net"
+	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	"fyne.io/fyne/v2"
@@ -22,46 +30,141 @@ import (
 	"fyne.io/systray"
 	"github.com/robfig/cron/v3"
 	"github.com/theHilikus/daily/internal/ui"
+	"golang.org/x/oauth2"
 	"google.golang.org/api/googleapi"
 )
 
 var (
 	displayDay      time.Time
 	eventsList      *fyne.Container
+	dayLabel        *widget.Label
+	tagFilter       string
+	allExpanded     bool
 	testCalendar    = flag.Bool("test-calendar", false, "Whether to use a dummy calendar instead of retrieving events from the real one")
 	verbose         = flag.Bool("verbose", false, "Enable extra debug logs")
+	fakeNow         = flag.String("fake-now", "", "Time (RFC3339) to pretend it is when the app starts. Useful to test notifications")
 	lastFullRefresh time.Time
 	lastErrorButton *widget.Button
+	snoozeButton    *widget.Button
+	previousDay     *widget.Button
+	nextDay         *widget.Button
 
-	eventSource EventSource
-	dailyApp    fyne.App
+	eventSource    EventSource
+	eventsMutex    sync.Mutex
+	refreshMutex   sync.Mutex
+	dailyApp       fyne.App
+	mainWindow     fyne.Window
+	settingsWindow fyne.Window
+	cronHandler    *cron.Cron
+	shuttingDown   atomic.Bool
+	retryTimer     *time.Timer
+	retryDelay     time.Duration
+	transientRetry int
+	renderedEvents = map[string]*ui.Event{}
+	seenUpdates    = map[string]time.Time{}
+	uiCalls        = make(chan func(), 10)
+	startUiCalls   sync.Once
 )
 
-const dayFormat = "Mon, Jan 02"
+const (
+	dayFormat   = "Mon, Jan 02"
+	clockFormat = "3:04:05PM"
+
+	// Minutes before an event starts to notify about it while in another meeting
+	quietNotificationTime = 1
+
+	minRetryDelay = 5 * time.Second
+	maxRetryDelay = time.Minute
+	// How long the confirmation that a link was copied is shown
+	copyConfirmationTime = 2 * time.Second
+	// Delay before retrying when rate limited without a Retry-After header
+	defaultRateLimitDelay = 15 * time.Second
+	// Quick retries of a refresh that failed with a transient error before reporting it
+	maxTransientRetries = 3
+)
+
+// Delay before retrying a refresh that failed with a transient error. It doubles on each retry
+var transientRetryDelay = 500 * time.Millisecond
+
+var (
+	htmlLineBreakRegex = regexp.MustCompile(`(?i)<br\s*/?>|</p>|</div>|</li>`)
+	htmlLinkRegex      = regexp.MustCompile(`(?is)<a\s[^>]*?href\s*=\s*["']([^"']*)["'][^>]*>(.*?)</a\s*>`)
+	htmlTagRegex       = regexp.MustCompile(`(?i)</?(?:a|abbr|b|big|blockquote|body|br|center|code|del|div|em|font|h[1-6]|head|hr|html|i|img|ins|label|li|meta|ol|p|pre|s|small|span|strike|strong|sub|sup|table|tbody|td|tfoot|th|thead|tr|u|ul)(?:\s[^>]*)?/?>`)
+	blankLinesRegex    = regexp.MustCompile(`\n{3,}`)
+	emailRegex         = regexp.MustCompile(`(?:mailto:)?[A-Za-z0-9._%+\-]+@[A-Za-z0-9.\-]+\.[A-Za-z]{2,}`)
+	phoneRegex         = regexp.MustCompile(`\+[0-9][0-9 ().\-]{6,}[0-9]|(?:\b1[ .\-]?)?(?:\([0-9]{3}\) ?|\b[0-9]{3}[.\-])[0-9]{3}[ .\-][0-9]{4}\b`)
+	tagRegex           = regexp.MustCompile(`(^|\s)#(\pL[\pL\pN_\-]*)`)
+	pinRegex           = regexp.MustCompile(`(?i)\b(?:pin|passcode|access code)\s*[:#]?\s*([0-9]+)`)
+)
 
 // An entity that can retrieve calendar events
 type EventSource interface {
 	// Gets a slice of events for the particular day specified
 	getEvents(time.Time, bool) ([]event, bool, error)
+	// Gets all the events currently buffered, regardless of the day they are on
+	getBufferedEvents() []event
+	// Gets the events that start in the range, retrieving them if they are not buffered
+	getEventsBetween(time.Time, time.Time) ([]event, error)
+}
+
+// An event source that can change the response of the user to the events they are invited to
+type eventResponder interface {
+	respond(*event, responseStatus) error
+}
+
+// A string set by background work and read by the UI, like a token obtained by an OAuth flow
+type lockedString struct {
+	value string
+	mutex sync.Mutex
+}
+
+func (locked *lockedString) get() string {
+	locked.mutex.Lock()
+	defer locked.mutex.Unlock()
+
+	return locked.value
+}
+
+func (locked *lockedString) set(value string) {
+	locked.mutex.Lock()
+	defer locked.mutex.Unlock()
+	locked.value = value
 }
 
 func main() {
 	flag.Parse()
 	configureLog()
+	configureClock()
 
 	slog.Info("Starting app")
 
-	window := buildUi()
+	dailyApp = app.NewWithID("com.github.theHilikus.daily")
+	if !listenForInstances() {
+		slog.Info("Daily is already running. Exiting")
+		return
+	}
 
-	calendarToken := dailyApp.Preferences().String("calendar-token")
-	if calendarToken != "" {
-		refresh(true)
+	applyAccentColor()
+	buildApp()
+	_, isDesktop := dailyApp.(desktop.App)
+	if dailyApp.Preferences().Bool("tray-only") && isDesktop {
+		slog.Info("Starting in the system tray only")
+	} else {
+		buildMainWindow().Show()
+	}
+
+	if isCalendarConfigured() {
+		if showCachedEvents() {
+			go refresh(true)
+		} else {
+			refresh(true)
+		}
 	} else {
 		slog.Info("Calendar config not found. Starting in Settings UI")
 		showSettings(dailyApp)
 	}
 
-	window.ShowAndRun()
+	dailyApp.Run()
 }
 
 func configureLog() {
@@ -82,23 +185,75 @@ func configureLog() {
 	slog.SetDefault(slog.New(handler))
 }
 
-func buildUi() fyne.Window {
-	displayDay = time.Now()
+// The clock used for everything related to events. It can be replaced to pretend it's a different time
+var now = time.Now
 
-	dailyApp = app.NewWithID("com.github.theHilikus.daily")
-	dailyApp.SetIcon(ui.ResourceAppIconPng)
+func configureClock() {
+	if *fakeNow == "" {
+		return
+	}
 
-	window := dailyApp.NewWindow("Daily")
-	window.Resize(fyne.NewSize(400, 600))
+	fakeStart, err := time.Parse(time.RFC3339, *fakeNow)
+	if err != nil {
+		slog.Error("Invalid fake-now. Using the real time", "error", err)
+		return
+	}
+
+	slog.Info("Using a fake clock starting at " + fakeStart.Format(time.RFC3339))
+	offset := time.Until(fakeStart)
+	now = func() time.Time {
+		return time.Now().Add(offset)
+	}
+}
+
+func buildApp() {
+	displayDay = now()
+
+	dailyApp.SetIcon(ui.ResourceAppIconPng)
 
 	if desk, ok := dailyApp.(desktop.App); ok {
-		showItem := fyne.NewMenuItem("Show", func() {
-			window.Show()
-		})
-		menu := fyne.NewMenu("Daily Systray Menu", showItem)
+		showItem := fyne.NewMenuItem(message("show"), showMainWindow)
+		snoozeItem := fyne.NewMenuItem(message("snooze-notifications"), nil)
+		snoozeItem.ChildMenu = fyne.NewMenu("",
+			fyne.NewMenuItem(message("snooze-15-minutes"), func() { snoozeNotifications(now().Add(15 * time.Minute)) }),
+			fyne.NewMenuItem(message("snooze-30-minutes"), func() { snoozeNotifications(now().Add(30 * time.Minute)) }),
+			fyne.NewMenuItem(message("snooze-1-hour"), func() { snoozeNotifications(now().Add(time.Hour)) }),
+			fyne.NewMenuItem(message("snooze-until-tomorrow"), func() {
+				year, month, day := now().AddDate(0, 0, 1).Date()
+				snoozeNotifications(time.Date(year, month, day, 0, 0, 0, 0, time.Local))
+			}),
+			fyne.NewMenuItemSeparator(),
+			fyne.NewMenuItem(message("resume-notifications"), unsnoozeNotifications),
+		)
+		quitItem := fyne.NewMenuItem(message("quit"), quit)
+		quitItem.IsQuit = true
+		menu := fyne.NewMenu("Daily Systray Menu", showItem, snoozeItem, fyne.NewMenuItemSeparator(), quitItem)
 		desk.SetSystemTrayMenu(menu)
 		systray.SetTitle("Daily")
+	}
+	updateSnoozeIndicator()
+
+	startCronJobs()
+	dailyApp.Lifecycle().SetOnStopped(func() {
+		slog.Info("App stopped")
+		shuttingDown.Store(true)
+		cronHandler.Stop()
+		stopListeningForInstances()
+	})
+}
+
+// Builds the window with the events. In tray-only mode, this doesn't happen until the user shows it, so everything
+// that updates the window has to check that it exists
+func buildMainWindow() fyne.Window {
+	window := dailyApp.NewWindow("Daily")
+	mainWindow = window
+	width := dailyApp.Preferences().FloatWithFallback("window-width", 400)
+	height := dailyApp.Preferences().FloatWithFallback("window-height", 600)
+	window.Resize(fyne.NewSize(float32(width), float32(height)))
+
+	if _, ok := dailyApp.(desktop.App); ok {
 		window.SetCloseIntercept(func() {
+			mainWindowHidden = true
 			window.Hide()
 		})
 	}
@@ -106,132 +261,1115 @@ func buildUi() fyne.Window {
 	lastErrorButton = widget.NewButtonWithIcon("", theme.WarningIcon(), func() {})
 	lastErrorButton.Importance = widget.DangerImportance
 	lastErrorButton.Hidden = true
+	snoozeButton = widget.NewButtonWithIcon("", theme.VolumeMuteIcon(), unsnoozeNotifications)
+	updateSnoozeIndicator()
+	dayLabel = widget.NewLabel(displayedPeriod())
+	dayLabel.TextStyle = fyne.TextStyle{Bold: true}
+
+	searchButton := widget.NewButtonWithIcon("", theme.SearchIcon(), showSearch)
+	tagButton := widget.NewButton("#", nil)
+	tagButton.OnTapped = func() { showTagFilter(tagButton) }
+	expandButton := widget.NewButtonWithIcon("", theme.MenuDropDownIcon(), nil)
+	expandButton.OnTapped = func() { toggleExpandAll(expandButton) }
+	weekButton := widget.NewButtonWithIcon("", theme.GridIcon(), nil)
+	weekButton.OnTapped = func() { toggleWeekView(weekButton) }
+	updateWeekButton(weekButton)
 	refreshButton := widget.NewButtonWithIcon("", theme.ViewRefreshIcon(), func() { refresh(true) })
 	settingsButton := widget.NewButtonWithIcon("", theme.SettingsIcon(), func() { showSettings(dailyApp) })
-	toolbar := container.NewHBox(layout.NewSpacer(), lastErrorButton, refreshButton, settingsButton)
+	quitButton := widget.NewButtonWithIcon("", theme.LogoutIcon(), quit)
+	// in narrow windows, the less important buttons are moved to a menu
+	overflowMenu := fyne.NewMenu("",
+		fyne.NewMenuItem(message("search"), showSearch),
+		fyne.NewMenuItem(message("settings"), func() { showSettings(dailyApp) }),
+		fyne.NewMenuItem(message("quit"), quit),
+	)
+	overflowButton := widget.NewButtonWithIcon("", theme.MoreVerticalIcon(), nil)
+	overflowButton.OnTapped = func() {
+		widget.ShowPopUpMenuAtRelativePosition(overflowMenu, window.Canvas(), fyne.NewPos(0, overflowButton.Size().Height), overflowButton)
+	}
+	toolbar := container.New(ui.NewOverflowLayout(overflowButton, searchButton, settingsButton, quitButton),
+		layout.NewSpacer(), snoozeButton, lastErrorButton, searchButton, tagButton, expandButton, weekButton, refreshButton, settingsButton, quitButton, overflowButton)
 
-	dayLabel := widget.NewLabel(displayDay.Format(dayFormat))
-	dayLabel.TextStyle = fyne.TextStyle{Bold: true}
 	dayBar := container.NewHBox(layout.NewSpacer(), dayLabel, layout.NewSpacer())
-	topBar := container.NewVBox(toolbar, dayBar)
+	if dailyApp.Preferences().Bool("show-clock") {
+		clockLabel := widget.NewLabel(now().Format(clockFormat))
+		dayBar.Objects = []fyne.CanvasObject{layout.NewSpacer(), dayLabel, clockLabel, layout.NewSpacer()}
+		go runClock(clockLabel)
+	}
+	changesBanner = container.NewVBox()
+	changesBanner.Hide()
+	topBar := container.NewVBox(toolbar, dayBar, changesBanner)
 
 	eventsList = container.NewVBox()
 
-	previousDay := widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() { changeDay(displayDay.AddDate(0, 0, -1), dayLabel) })
-	nextDay := widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() { changeDay(displayDay.AddDate(0, 0, 1), dayLabel) })
-	bottomBar := container.NewHBox(layout.NewSpacer(), previousDay, layout.NewSpacer(), nextDay, layout.NewSpacer())
+	previousDay = widget.NewButtonWithIcon("", theme.NavigateBackIcon(), func() { changeDay(displayDay.AddDate(0, 0, -navigationStep())) })
+	nextDay = widget.NewButtonWithIcon("", theme.NavigateNextIcon(), func() { changeDay(displayDay.AddDate(0, 0, navigationStep())) })
+	todayButton := widget.NewButton(message("today"), func() { changeDay(now()) })
+	todayButton.Importance = widget.LowImportance
+	updateNavigationButtons()
+	bottomBar := container.NewHBox(layout.NewSpacer(), previousDay, layout.NewSpacer(), todayButton, layout.NewSpacer(), nextDay, layout.NewSpacer())
 
-	content := container.NewBorder(topBar, bottomBar, nil, nil, eventsList)
+	content := container.NewBorder(topBar, bottomBar, nil, nil, container.NewVScroll(eventsList))
 	window.SetContent(content)
 
-	cronHandler := cron.New()
+	return window
+}
+
+// Updates the clock every second until the app shuts down. Only the label is updated, not the events
+func runClock(clockLabel *widget.Label) {
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		if shuttingDown.Load() {
+			return
+		}
+		clockLabel.SetText(now().Format(clockFormat))
+	}
+}
+
+func showMainWindow() {
+	if mainWindow == nil {
+		slog.Info("Building main window")
+		buildMainWindow()
+		refresh(false)
+	}
+	mainWindowHidden = false
+	mainWindow.Show()
+	showPendingChanges()
+}
+
+// Brings the main window to the front when the notification of the event is clicked, with the day of the event and
+// its details open
+func showNotifiedEvent(notified event) {
+	showMainWindow()
+	mainWindow.RequestFocus()
+	if !isOnSameDay(displayDay, notified.start) {
+		changeDay(notified.start)
+	}
+	// the event is not found if it changed since it was notified
+	if eventWidget, found := renderedEvents[notified.renderKey()]; found {
+		eventWidget.Open()
+	}
+}
+
+func startCronJobs() {
+	cronHandler = cron.New()
 	cronHandler.AddFunc("* * * * *", func() { refresh(false) })
-	cronHandler.AddFunc("0 0 * * *", func() { changeDay(time.Now(), dayLabel) })
+	cronHandler.AddFunc("* * * * *", func() {
+		if events, found := getBufferedEvents(); found {
+			checkNotifications(events)
+			events = dimBlockedEvents(events)
+			exportStatus(events)
+			updateSlackStatus(events)
+			updateStatusWebhook(events)
+		}
+	})
+	cronHandler.AddFunc("0 0 * * *", func() {
+		changeDay(now())
+		clearOldNotified()
+	})
+	if summaryTime := dailyApp.Preferences().String("weekly-summary-time"); summaryTime != "" {
+		addScheduledJob(summaryTime, dailyApp.Preferences().IntWithFallback("weekly-summary-day", int(time.Monday)), sendWeeklySummary)
+	}
+	if briefingTime := dailyApp.Preferences().String("morning-briefing-time"); briefingTime != "" {
+		addScheduledJob(briefingTime, -1, sendMorningBriefing)
+	}
 	cronHandler.Start()
+}
 
-	return window
+// Schedules a job at a time of the day (HH:MM) on a day of the week (0 is Sunday). A negative day schedules it every
+// day
+func addScheduledJob(timeOfDay string, weekday int, job func()) {
+	spec, err := createCronSpec(timeOfDay, weekday)
+	if err != nil {
+		slog.Error("Invalid time to schedule job: "+timeOfDay, "error", err)
+		return
+	}
+
+	slog.Debug("Scheduling job at " + spec)
+	_, err = cronHandler.AddFunc(spec, job)
+	if err != nil {
+		slog.Error("Could not schedule job", "error", err)
+	}
+}
+
+func createCronSpec(timeOfDay string, weekday int) (string, error) {
+	parsed, err := time.Parse("15:04", timeOfDay)
+	if err != nil {
+		return "", err
+	}
+
+	day := "*"
+	if weekday >= 0 {
+		day = strconv.Itoa(weekday % 7)
+	}
+
+	return fmt.Sprintf("%d %d * * %s", parsed.Minute(), parsed.Hour(), day), nil
+}
+
+// Sends a notification with the number of meetings and how long they take in the next 7 days
+func sendWeeklySummary() {
+	if shuttingDown.Load() {
+		return
+	}
+
+	start := startOfDay(now())
+	events, found, err := getEventsBetween(start, start.AddDate(0, 0, 7))
+	if !found {
+		return
+	}
+	if err != nil {
+		slog.Error("Could not retrieve the events of the week", "error", err)
+		return
+	}
+
+	count, total := summarizeEvents(events)
+	slog.Info("Sending weekly summary")
+	sendNotification(fyne.NewNotification(message("weekly-summary"), fmt.Sprintf(message("meetings-summary"), count, createUserFriendlyDurationText(total))), notificationActions{})
+}
+
+// Sends a notification with the first meeting of the day, how many there are and when there is time for other things
+func sendMorningBriefing() {
+	if shuttingDown.Load() {
+		return
+	}
+
+	start := startOfDay(now())
+	events, found, err := getEventsBetween(start, start.AddDate(0, 0, 1))
+	if !found {
+		return
+	}
+	if err != nil {
+		slog.Error("Could not retrieve the events of the day", "error", err)
+		return
+	}
+
+	slog.Info("Sending morning briefing")
+	count, total := summarizeEvents(events)
+	if count == 0 {
+		sendNotification(fyne.NewNotification(message("morning-briefing"), message("no-events")), notificationActions{})
+		return
+	}
+
+	var lines []string
+	for _, event := range events {
+		if event.response != declined {
+			lines = append(lines, message("first-meeting")+event.start.Format("3:04PM ")+event.title)
+			break
+		}
+	}
+	lines = append(lines, fmt.Sprintf(message("meetings-summary"), count, createUserFriendlyDurationText(total)))
+	freeSlot := findFreeSlot(events, now(), time.Duration(dailyApp.Preferences().IntWithFallback("free-slot-minutes", 30))*time.Minute)
+	lines = append(lines, message("first-free-slot")+freeSlot.Format("3:04PM"))
+	sendNotification(fyne.NewNotification(message("morning-briefing"), strings.Join(lines, "\n")), notificationActions{})
+}
+
+// Gets when the first period of at least the given duration without meetings starts, from the given time on. Declined
+// meetings don't count
+func findFreeSlot(events []event, from time.Time, duration time.Duration) time.Time {
+	sorted := slices.Clone(events)
+	slices.SortFunc(sorted, func(one event, other event) int { return one.start.Compare(other.start) })
+
+	result := from
+	for _, event := range sorted {
+		if event.response == declined || !event.end.After(result) {
+			continue
+		}
+		if event.start.Sub(result) >= duration {
+			break
+		}
+		result = maxTime(result, event.end)
+	}
+
+	return result
+}
+
+// Gets how many meetings there are and how long they take in total, leaving out declined ones
+func summarizeEvents(events []event) (int, time.Duration) {
+	count := 0
+	var total time.Duration
+	for _, event := range events {
+		if event.response == declined {
+			continue
+		}
+		count++
+		total += event.end.Sub(event.start)
+	}
+
+	return count, total
+}
+
+func quit() {
+	slog.Info("Quitting app")
+	shuttingDown.Store(true)
+	cronHandler.Stop()
+
+	if settingsWindow != nil {
+		settingsWindow.Close()
+	}
+
+	if mainWindow != nil {
+		size := mainWindow.Canvas().Size()
+		dailyApp.Preferences().SetFloat("window-width", float64(size.Width))
+		dailyApp.Preferences().SetFloat("window-height", float64(size.Height))
+	}
+
+	dailyApp.Quit()
 }
 
 func refresh(fullRefresh bool) {
-	if dailyApp.Preferences().String("calendar-token") == "" {
-		slog.Warn("Not refreshing. No calendar-token found")
+	// the cron jobs, the startup and the UI can refresh at the same time
+	refreshMutex.Lock()
+	defer refreshMutex.Unlock()
+	if shuttingDown.Load() {
+		slog.Debug("Not refreshing. App is shutting down")
 		return
 	}
 
+	if !isCalendarConfigured() {
+		slog.Warn("Not refreshing. No calendar-token or ics-url found")
+		return
+	}
+
+	updateSnoozeIndicator()
+
 	slog.Info("Refreshing UI for date " + displayDay.Format("2006-01-02") + ". Full Refresh = " + strconv.FormatBool(fullRefresh))
-	eventsList.RemoveAll()
 	events, err := getEvents(fullRefresh)
+	if delay, limited := rateLimitDelay(err); limited {
+		// it's not something the user can fix so the current events are kept and no error is shown
+		slog.Warn("Google Calendar is rate limiting requests. Retrying in "+delay.String(), "error", err)
+		scheduleRetryIn(delay)
+		return
+	}
+	if isTransientError(err) && transientRetry < maxTransientRetries {
+		// retried from a timer so the UI doesn't wait. The current events are kept in the meantime
+		delay := transientRetryDelay << transientRetry
+		transientRetry++
+		slog.Warn(fmt.Sprintf("Transient error retrieving the events. Retry %d in %s", transientRetry, delay), "error", err)
+		scheduleRetryIn(delay)
+		return
+	}
+	transientRetry = 0
 	if err != nil {
-		slog.Error("Could not retrieve calendar events", "error", err)
-
-		userErrorMessage := "Could not retrieve calendar events:\n"
-		switch e := err.(type) {
-		case *googleapi.Error:
-			userErrorMessage += e.Message
-		case *url.Error:
-			userErrorMessage += e.Err.Error()
-		default:
-			userErrorMessage += err.Error()
-		}
-
-		reportUserError(userErrorMessage)
+		handleEventRetrievalError(err)
 		showNoEvents()
+		scheduleRetry()
 		return
-	} else if !lastErrorButton.Hidden {
+	} else if lastErrorButton != nil && !lastErrorButton.Hidden {
 		reportUserError("") // clear the error
 	}
+	cancelRetry()
+
+	if mainWindow == nil {
+		slog.Debug("Not rendering events. The main window was not built yet")
+		return
+	}
+
+	showEvents(events)
+}
+
+// Shows the events of the displayed day that were saved by the last run, if any, so that the window isn't empty while
+// the latest ones are retrieved
+func showCachedEvents() bool {
+	if *testCalendar || mainWindow == nil {
+		return false
+	}
+	_, _, cachedEvents, err := loadEventsCache()
+	if err != nil {
+		slog.Debug("No cached events to show", "error", err)
+		return false
+	}
+
+	var events []event
+	for _, event := range cachedEvents {
+		if isDisplayed(event.start) {
+			events = append(events, event)
+		}
+	}
+	slog.Info("Showing " + strconv.Itoa(len(events)) + " cached event(s) until the latest ones are retrieved")
+	showEvents(events)
+
+	return true
+}
 
+// Applies the display preferences to the events and renders them
+func showEvents(events []event) {
+	events = applyDefaultDuration(events, dailyApp.Preferences().IntWithFallback("default-event-duration", 0))
+	blocklist, err := compileBlocklist(dailyApp.Preferences().StringList("title-blocklist"))
+	if err != nil {
+		slog.Warn("Ignoring invalid title-blocklist patterns", "error", err)
+		reportUserError(message("invalid-blocklist") + "\n" + err.Error())
+	}
+	events = applyBlocklist(events, blocklist, dailyApp.Preferences().Bool("title-blocklist-dim"))
+	events = filterByTag(events, tagFilter)
+	events = filterDeclined(events, dailyApp.Preferences().Bool("hide-declined"))
 	if len(events) == 0 {
 		showNoEvents()
+		return
 	}
 
+	renderEvents(events)
+}
+
+// Shows the events in the list. Rows of events that didn't change are reused to avoid flickering and to keep their
+// state, but their time-dependent parts and response are always updated so that changes picked up by a refresh show
+// right away
+func renderEvents(events []event) {
+	truncateTitles := dailyApp.Preferences().Bool("truncate-titles")
+	detailMaxHeight := dailyApp.Preferences().Int("detail-max-height")
+	var rows []fyne.CanvasObject
+	newRenderedEvents := make(map[string]*ui.Event)
+	weekView := isWeekView()
+	var headerDay time.Time
 	for pos := range events {
 		event := &events[pos]
-		eventText := event.start.Format("3:04-") + event.end.Format("3:04PM ") + event.title
+		if weekView && !isOnSameDay(headerDay, event.start) {
+			headerDay = event.start
+			rows = append(rows, createDayHeader(headerDay))
+		}
+		eventText := createEventTitle(event)
 		eventStyle := fyne.TextStyle{}
 		eventColour := theme.DefaultTheme().Color(theme.ColorNameForeground, theme.VariantLight)
-		if event.isFinished() {
-			//past events
+		if event.isFinished() || event.dimmed {
+			//past or blocked events
 			eventColour = theme.DefaultTheme().Color(theme.ColorNameDisabled, theme.VariantLight)
 		} else if event.isStarted() {
 			//ongoing events
-			timeToEnd := time.Until(event.end)
-			eventText += " (" + createUserFriendlyDurationText(timeToEnd) + " remaining)"
 			eventStyle.Bold = true
+		}
+
+		responseIcon := event.responseIcon()
+
+		var buttons []*widget.Button
+		if event.isVirtualMeeting() {
+			locationUrl, err := url.Parse(event.location)
+			if err == nil {
+				meetingButton := widget.NewButtonWithIcon("", event.meetingIcon(), func() { openUrl(locationUrl) })
+				if event.isFinished() {
+					meetingButton.Disable()
+				} else if isJoinTime(event) {
+					meetingButton.Importance = widget.HighImportance
+				}
+				buttons = append(buttons, meetingButton)
+				if !event.isFinished() {
+					copyButton := widget.NewButtonWithIcon("", theme.ContentCopyIcon(), nil)
+					copyButton.OnTapped = func() { copyMeetingLink(copyButton, locationUrl.String()) }
+					buttons = append(buttons, copyButton)
+				}
+			}
+		}
+		if responder, ok := currentEventSource().(eventResponder); ok && event.response != empty && !event.organizer && !event.isFinished() {
+			buttons = append(buttons, createResponseButton(responder, *event))
+		}
+		if event.htmlLink != "" {
+			htmlUrl, err := url.Parse(event.htmlLink)
+			if err == nil {
+				browserButton := widget.NewButtonWithIcon("", event.browserIcon(), func() { openUrl(htmlUrl) })
+				buttons = append(buttons, browserButton)
+			}
+		}
+
+		key := event.renderKey()
+		eventWidget, found := renderedEvents[key]
+		if found {
+			eventWidget.Title.Update(eventText, eventStyle, eventColour)
+			eventWidget.Icon.SetResource(responseIcon)
+			eventWidget.SetTitleButtons(buttons)
 		} else {
-			//future events
-			timeToStart := time.Until(event.start)
-			eventText += " (in " + createUserFriendlyDurationText(timeToStart) + ")"
-
-			if timeToStart.Minutes() <= float64(dailyApp.Preferences().IntWithFallback("notification-time", 1)) {
-				if event.notifiable {
-					notify(event, timeToStart)
-				} else {
-					slog.Debug("Not notifying for `" + event.title + "` because it is not notifiable")
+			title := ui.NewClickableText(eventText, eventStyle, eventColour)
+			details := createDetailsSegments(removeTags(cleanEventDetails(event.details)))
+			detail := container.NewVBox(widget.NewRichText(details...))
+			if event.location != "" && !event.isVirtualMeeting() {
+				detail.Objects = append([]fyne.CanvasObject{createLocationLink(event.location)}, detail.Objects...)
+			}
+			if !event.dialIn.isEmpty() {
+				dialInText := event.dialIn.clipboardText()
+				detail.Add(widget.NewButtonWithIcon(message("copy-dial-in"), theme.ContentCopyIcon(), func() {
+					mainWindow.Clipboard().SetContent(dialInText)
+				}))
+			}
+			if truncateTitles {
+				// the title might not be fully visible
+				fullTitle := widget.NewLabelWithStyle(event.title, fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+				fullTitle.Wrapping = fyne.TextWrapWord
+				detail.Objects = append([]fyne.CanvasObject{fullTitle}, detail.Objects...)
+			}
+			for _, attachment := range event.attachments {
+				attachmentUrl, err := url.Parse(attachment.url)
+				if err != nil {
+					slog.Warn("Ignoring attachment with invalid url", "title", attachment.title, "error", err)
+					continue
 				}
+				detail.Add(widget.NewHyperlink(attachment.title, attachmentUrl))
+			}
+
+			eventWidget = ui.NewEvent(widget.NewIcon(responseIcon), title, buttons, detail)
+			if allExpanded {
+				eventWidget.Open()
+			}
+		}
+		eventWidget.Title.Truncate = truncateTitles
+		configureTapActions(eventWidget, eventWidget.Title, event)
+		flagIfUpdated(eventWidget, event)
+		eventWidget.SetMarker(createEventMarker(event))
+		eventWidget.SetSubtitle(createEventSubtitle(event))
+		eventWidget.SetProgress(meetingProgress(event))
+		eventWidget.SetTags(event.tags)
+		eventWidget.SetDetailMaxHeight(float32(detailMaxHeight))
+		newRenderedEvents[key] = eventWidget
+		rows = append(rows, eventWidget)
+	}
+
+	renderedEvents = newRenderedEvents
+	eventsList.Objects = rows
+	eventsList.Refresh()
+}
+
+// Opens the details of all the events, or closes them if they were all opened. The button shows what tapping it
+// again does
+func toggleExpandAll(expandButton *widget.Button) {
+	allExpanded = !allExpanded
+	for _, row := range eventsList.Objects {
+		if eventWidget, isEvent := row.(*ui.Event); isEvent {
+			if allExpanded {
+				eventWidget.Open()
+			} else {
+				eventWidget.Close()
+			}
+		}
+	}
+
+	if allExpanded {
+		expandButton.SetIcon(theme.MenuDropUpIcon())
+	} else {
+		expandButton.SetIcon(theme.MenuDropDownIcon())
+	}
+}
+
+// Creates a link that opens the location of an in-person event in Google Maps
+func createLocationLink(location string) *widget.Hyperlink {
+	mapsUrl := &url.URL{Scheme: "https", Host: "maps.google.com", Path: "/", RawQuery: url.Values{"q": {location}}.Encode()}
+	result := widget.NewHyperlink(location, mapsUrl)
+	result.Wrapping = fyne.TextWrapWord
+	result.OnTapped = func() { openUrl(mapsUrl) }
+
+	return result
+}
+
+// Copies the link to join a meeting to the clipboard. The button shows a check mark for a moment to confirm it
+func copyMeetingLink(copyButton *widget.Button, link string) {
+	mainWindow.Clipboard().SetContent(link)
+	copyButton.SetIcon(theme.ConfirmIcon())
+	time.AfterFunc(copyConfirmationTime, func() { copyButton.SetIcon(theme.ContentCopyIcon()) })
+}
+
+// Creates the button that shows the responses the user can give to an invitation, with the current one checked
+func createResponseButton(responder eventResponder, target event) *widget.Button {
+	responseButton := widget.NewButtonWithIcon("", theme.MailReplyIcon(), nil)
+	responseButton.OnTapped = func() {
+		var items []*fyne.MenuItem
+		for _, response := range []responseStatus{accepted, tentative, declined} {
+			item := fyne.NewMenuItem(message("respond-"+string(response)), func() {
+				go respondToEvent(responder, target, response)
+			})
+			item.Checked = target.response == response
+			items = append(items, item)
+		}
+		widget.ShowPopUpMenuAtRelativePosition(fyne.NewMenu("", items...), mainWindow.Canvas(), fyne.NewPos(0, responseButton.Size().Height), responseButton)
+	}
+
+	return responseButton
+}
+
+// Sends the response and shows the updated event from the buffer, without retrieving all the events again
+func respondToEvent(responder eventResponder, target event, response responseStatus) {
+	if target.response == response {
+		return
+	}
+
+	// the response updates the buffered event
+	eventsMutex.Lock()
+	err := responder.respond(&target, response)
+	eventsMutex.Unlock()
+	if err != nil {
+		slog.Error("Could not respond to event "+target.id, "error", err)
+		reportUserError(message("response-failed") + "\n" + err.Error())
+		return
+	}
+	refresh(false)
+}
+
+// Creates the text shown in the row of an event, with its times, title and how long until it starts or ends
+func createEventTitle(event *event) string {
+	// rounding is only for display so notifications still use the real times
+	roundMinutes := dailyApp.Preferences().Int("round-times-minutes")
+	start, end := roundDisplayTime(event.start, roundMinutes), roundDisplayTime(event.end, roundMinutes)
+	result := start.Format("3:04-") + end.Format("3:04PM ") + event.title
+	if event.recurring && dailyApp.Preferences().BoolWithFallback("show-recurring-indicator", true) {
+		result += " 🗘"
+	}
+
+	if event.isStarted() {
+		timeToEnd := event.end.Sub(now())
+		result += " (" + createUserFriendlyDurationText(timeToEnd) + " remaining)"
+	} else if !event.isFinished() {
+		timeToStart := event.start.Sub(now())
+		result += " (in " + createUserFriendlyDurationText(timeToStart) + ")"
+	}
+
+	return result
+}
+
+// Rounds the time to the nearest multiple of the minutes since the start of its day. Minutes of 1 or less leave it
+// unchanged
+func roundDisplayTime(value time.Time, minutes int) time.Time {
+	if minutes <= 1 {
+		return value
+	}
+
+	dayStart := startOfDay(value)
+	return dayStart.Add(value.Sub(dayStart).Round(time.Duration(minutes) * time.Minute))
+}
+
+// Creates the line under the title with the physical location of the event, if the show-event-source preference is set
+// the calendar it comes from, and whether it is shown as free. Locations of virtual meetings are left out since they have a join button
+func createEventSubtitle(event *event) string {
+	var parts []string
+	if event.location != "" && !event.isVirtualMeeting() {
+		parts = append(parts, event.location)
+	}
+	if dailyApp.Preferences().Bool("show-event-source") && event.source != "" {
+		parts = append(parts, event.source)
+	}
+	if event.free {
+		parts = append(parts, message("shown-as-free"))
+	}
+
+	return strings.Join(parts, " · ")
+}
+
+// Gets how far through the event we are, between 0 and 1, if the show-meeting-progress preference is set and the event
+// is ongoing. Negative otherwise
+func meetingProgress(event *event) float64 {
+	if !event.isStarted() || !dailyApp.Preferences().Bool("show-meeting-progress") {
+		return -1
+	}
+
+	return float64(now().Sub(event.start)) / float64(event.end.Sub(event.start))
+}
+
+// Gets the icon that marks meetings the user organizes, if the highlight-organized preference is set
+func createEventMarker(event *event) fyne.Resource {
+	if event.organizer && dailyApp.Preferences().Bool("highlight-organized") {
+		return theme.AccountIcon()
+	}
+
+	return nil
+}
+
+// Shows a badge on events that changed since the user last viewed them. The badge is cleared when the event is
+// expanded
+func flagIfUpdated(eventWidget *ui.Event, event *event) {
+	seenUpdate, known := seenUpdates[event.id]
+	if !known {
+		seenUpdates[event.id] = event.updated
+		return
+	}
+
+	if event.updated.After(seenUpdate) {
+		eventWidget.ShowBadge(theme.InfoIcon())
+		id, updated := event.id, event.updated
+		eventWidget.OnOpened = func() {
+			seenUpdates[id] = updated
+			eventWidget.HideBadge()
+		}
+	}
+}
+
+// Sets what tapping the event title does based on the tap-action preference. The secondary tap (right-click or
+// long-press) does the alternate action
+func configureTapActions(eventWidget *ui.Event, title *ui.ClickableText, event *event) {
+	toggleDetails := func(*fyne.PointEvent) { eventWidget.Toggle() }
+	joinMeeting := openUrlAction(event.location)
+	if !event.isVirtualMeeting() {
+		joinMeeting = nil
+	}
+
+	tapAction := dailyApp.Preferences().StringWithFallback("tap-action", "details")
+	openInBrowser := openUrlAction(event.htmlLink)
+	switch {
+	case tapAction == "join" && joinMeeting != nil:
+		title.OnTapped = joinMeeting
+		title.OnTappedSecondary = toggleDetails
+	case tapAction == "browser" && openInBrowser != nil:
+		title.OnTapped = openInBrowser
+		title.OnTappedSecondary = toggleDetails
+	default:
+		title.OnTapped = toggleDetails
+		title.OnTappedSecondary = joinMeeting
+	}
+}
+
+func openUrlAction(link string) func(*fyne.PointEvent) {
+	if link == "" {
+		return nil
+	}
+	parsedUrl, err := url.Parse(link)
+	if err != nil {
+		slog.Warn("Invalid url "+link, "error", err)
+		return nil
+	}
+
+	return func(*fyne.PointEvent) { openUrl(parsedUrl) }
+}
+
+// Converts the (possibly HTML) details of an event into readable plain text. Only known tags are removed, so text like
+// <TBD> is kept
+func cleanEventDetails(details string) string {
+	result := htmlLineBreakRegex.ReplaceAllString(details, "\n")
+	result = htmlLinkRegex.ReplaceAllStringFunc(result, showLinkAddress)
+	result = htmlTagRegex.ReplaceAllString(result, "")
+	result = html.UnescapeString(result)
+	result = blankLinesRegex.ReplaceAllString(result, "\n\n")
+
+	return strings.TrimSpace(result)
+}
+
+// Gets the text of an HTML link followed by its address, so that the address isn't lost with the tags. Links whose text
+// is the address only keep the text
+func showLinkAddress(link string) string {
+	match := htmlLinkRegex.FindStringSubmatch(link)
+	address := match[1]
+	text := strings.TrimSpace(htmlTagRegex.ReplaceAllString(match[2], ""))
+	if text == "" {
+		return address
+	}
+	if unescaped := html.UnescapeString(text); unescaped == html.UnescapeString(address) || "mailto:"+unescaped == html.UnescapeString(address) {
+		return text
+	}
+
+	return text + " (" + address + ")"
+}
+
+// Gets the hashtags in the details of an event, in lowercase and without the #
+func extractTags(details string) []string {
+	var result []string
+	for _, match := range tagRegex.FindAllStringSubmatch(cleanEventDetails(details), -1) {
+		tag := strings.ToLower(match[2])
+		if !slices.Contains(result, tag) {
+			result = append(result, tag)
+		}
+	}
+
+	return result
+}
+
+// Removes the hashtags from the details since they are shown separately
+func removeTags(details string) string {
+	return strings.TrimSpace(tagRegex.ReplaceAllString(details, "$1"))
+}
+
+// Gets the events that have the tag. An empty tag doesn't filter
+func filterByTag(events []event, tag string) []event {
+	if tag == "" {
+		return events
+	}
+
+	var result []event
+	for _, event := range events {
+		if slices.Contains(event.tags, tag) {
+			result = append(result, event)
+		}
+	}
+
+	return result
+}
+
+// Leaves out the events the user declined if they are hidden. They are only left out of the list, so they are still
+// buffered and searchable
+func filterDeclined(events []event, hide bool) []event {
+	if !hide {
+		return events
+	}
+
+	var result []event
+	for _, event := range events {
+		if event.response != declined {
+			result = append(result, event)
+		}
+	}
+
+	return result
+}
+
+// Shows a menu to only display the events with one of the tags of the buffered events
+func showTagFilter(tagButton *widget.Button) {
+	events, found := getBufferedEvents()
+	if !found {
+		return
+	}
+
+	var tags []string
+	for _, event := range events {
+		for _, tag := range event.tags {
+			if !slices.Contains(tags, tag) {
+				tags = append(tags, tag)
 			}
 		}
+	}
+	slices.Sort(tags)
+
+	allItem := fyne.NewMenuItem(message("all-tags"), func() { setTagFilter("", tagButton) })
+	allItem.Checked = tagFilter == ""
+	items := []*fyne.MenuItem{allItem}
+	for _, tag := range tags {
+		item := fyne.NewMenuItem("#"+tag, func() { setTagFilter(tag, tagButton) })
+		item.Checked = tag == tagFilter
+		items = append(items, item)
+	}
+	widget.ShowPopUpMenuAtRelativePosition(fyne.NewMenu("", items...), mainWindow.Canvas(), fyne.NewPos(0, tagButton.Size().Height), tagButton)
+}
+
+func setTagFilter(tag string, tagButton *widget.Button) {
+	slog.Info("Filtering events by tag '" + tag + "'")
+	tagFilter = tag
+	tagButton.SetText("#" + tag)
+	if tag == "" {
+		tagButton.Importance = widget.MediumImportance
+	} else {
+		tagButton.Importance = widget.HighImportance
+	}
+	tagButton.Refresh()
+	refresh(false)
+}
+
+// Splits the details text into segments, turning email addresses into mailto links
+func createDetailsSegments(details string) []widget.RichTextSegment {
+	var result []widget.RichTextSegment
+	start := 0
+	for _, match := range emailRegex.FindAllStringIndex(details, -1) {
+		if match[0] > start {
+			result = append(result, &widget.TextSegment{Text: details[start:match[0]], Style: widget.RichTextStyleInline})
+		}
+		address := strings.TrimPrefix(details[match[0]:match[1]], "mailto:")
+		result = append(result, &widget.HyperlinkSegment{Text: address, URL: &url.URL{Scheme: "mailto", Opaque: address}})
+		start = match[1]
+	}
+	if start < len(details) || len(result) == 0 {
+		result = append(result, &widget.TextSegment{Text: details[start:], Style: widget.RichTextStyleInline})
+	}
+
+	return result
+}
+
+// Sends notifications for the upcoming events that are about to start
+func checkNotifications(events []event) {
+	if shuttingDown.Load() {
+		return
+	}
+
+	notificationTimes := defaultNotificationTimes()
+	quiet := dailyApp.Preferences().Bool("quiet-during-meetings") && isInMeeting(events)
+	if quiet {
+		slog.Debug("Currently in a meeting. Delaying notifications until shortly before events start")
+	}
+
+	for pos := range events {
+		event := &events[pos]
+		if event.isStarted() || event.isFinished() {
+			continue
+		}
+
+		reminders := event.reminderTimes(notificationTimes)
+		if quiet {
+			for i := range reminders {
+				reminders[i] = min(reminders[i], quietNotificationTime)
+			}
+		}
+
+		timeToStart := event.start.Sub(now())
+		if reminder, due := dueReminder(reminders, timeToStart); due {
+			// each reminder of an event is notified once
+			notificationKey := event.id + "@" + strconv.Itoa(reminder)
+			if !event.notifiable {
+				slog.Debug("Not notifying for `" + event.title + "` because it is not notifiable")
+			} else if isNotified(notificationKey) {
+				slog.Debug("Not notifying for `" + event.title + "` because it was already notified")
+			} else {
+				notify(event, timeToStart, notificationKey)
+			}
+		}
+	}
+}
+
+// Gets the minutes before the start to notify about events without reminders of their own. The user can set a single
+// value or a comma-separated list like "10,1" to be notified at each of them. Unless the user set it, the default
+// reminder of the calendar is used
+func defaultNotificationTimes() []int {
+	var result []int
+	for _, value := range strings.Split(dailyApp.Preferences().String("notification-time"), ",") {
+		value = strings.TrimSpace(value)
+		if value == "" {
+			continue
+		}
+		minutes, err := strconv.Atoi(value)
+		if err != nil || minutes < 0 {
+			slog.Warn("Ignoring invalid notification time " + value)
+			continue
+		}
+		if !slices.Contains(result, minutes) {
+			result = append(result, minutes)
+		}
+	}
+	if len(result) == 0 {
+		// older versions stored a single number
+		result = []int{dailyApp.Preferences().IntWithFallback("notification-time", dailyApp.Preferences().IntWithFallback("calendar-default-reminder", 1))}
+	}
+
+	return result
+}
+
+// Gets the latest of the reminders (in minutes before the start) that was reached, if any
+func dueReminder(reminders []int, timeToStart time.Duration) (int, bool) {
+	result := -1
+	for _, reminder := range reminders {
+		if timeToStart.Minutes() <= float64(reminder) && (result < 0 || reminder < result) {
+			result = reminder
+		}
+	}
+
+	return result, result >= 0
+}
+
+// Gives events without duration the default duration (in minutes) for display. A negative default duration hides them
+// instead. The original events are not modified
+func applyDefaultDuration(events []event, defaultDuration int) []event {
+	if defaultDuration == 0 {
+		return events
+	}
+
+	var result []event
+	for _, event := range events {
+		if event.start.Equal(event.end) {
+			if defaultDuration < 0 {
+				slog.Debug("Hiding event without duration: " + event.title)
+				continue
+			}
+			event.end = event.start.Add(time.Duration(defaultDuration) * time.Minute)
+		}
+		result = append(result, event)
+	}
+
+	return result
+}
+
+// Compiles the title blocklist patterns. Invalid patterns are skipped and reported in the error
+func compileBlocklist(patterns []string) ([]*regexp.Regexp, error) {
+	var result []*regexp.Regexp
+	var errs []error
+	for _, pattern := range patterns {
+		compiled, err := regexp.Compile(pattern)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		result = append(result, compiled)
+	}
+
+	return result, errors.Join(errs...)
+}
+
+// Hides the events whose title matches any of the blocklist patterns or, if dim is set, marks them to be dimmed
+// instead. The original events are not modified
+func applyBlocklist(events []event, blocklist []*regexp.Regexp, dim bool) []event {
+	if len(blocklist) == 0 {
+		return events
+	}
+
+	var result []event
+	for _, event := range events {
+		if slices.ContainsFunc(blocklist, func(pattern *regexp.Regexp) bool { return pattern.MatchString(event.title) }) {
+			if !dim {
+				slog.Debug("Hiding blocked event: " + event.title)
+				continue
+			}
+			event.dimmed = true
+		}
+		result = append(result, event)
+	}
+
+	return result
+}
+
+// Marks the events whose title matches the title-blocklist preference to be dimmed, so that they don't make the user
+// busy even when they are hidden. Invalid patterns are ignored since they are reported when the events are shown
+func dimBlockedEvents(events []event) []event {
+	blocklist, _ := compileBlocklist(dailyApp.Preferences().StringList("title-blocklist"))
+	return applyBlocklist(events, blocklist, true)
+}
+
+// Whether it is time to join the meeting, from when it is last notified until a grace period after it starts
+func isJoinTime(event *event) bool {
+	notificationTime := time.Duration(slices.Min(event.reminderTimes(defaultNotificationTimes()))) * time.Minute
+	gracePeriod := time.Duration(dailyApp.Preferences().IntWithFallback("join-emphasis-grace", 5)) * time.Minute
+	currentTime := now()
+	return currentTime.After(event.start.Add(-notificationTime)) && currentTime.Before(event.start.Add(gracePeriod))
+}
+
+// Whether any of the events is currently ongoing and not declined
+func isInMeeting(events []event) bool {
+	for pos := range events {
+		if events[pos].isStarted() && events[pos].response != declined {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Schedules a refresh after a failed one, doubling the delay after each consecutive failure
+func scheduleRetry() {
+	if retryTimer != nil {
+		retryTimer.Stop()
+	}
+
+	if retryDelay == 0 {
+		retryDelay = minRetryDelay
+	} else {
+		retryDelay = min(retryDelay*2, maxRetryDelay)
+	}
+
+	slog.Info("Retrying refresh in " + retryDelay.String())
+	retryTimer = time.AfterFunc(retryDelay, func() { refresh(true) })
+}
+
+// Schedules a single retry after the delay, replacing any scheduled one. The backoff of scheduleRetry is not affected
+func scheduleRetryIn(delay time.Duration) {
+	if retryTimer != nil {
+		retryTimer.Stop()
+	}
+
+	retryTimer = time.AfterFunc(delay, func() { refresh(true) })
+}
+
+func cancelRetry() {
+	if retryTimer != nil {
+		slog.Debug("Cancelling scheduled retry")
+		retryTimer.Stop()
+		retryTimer = nil
+	}
+	retryDelay = 0
+}
+
+func handleEventRetrievalError(err error) {
+	slog.Error("Could not retrieve calendar events", "error", err)
+	reportUserError(createUserErrorMessage(err))
+}
+
+func createUserErrorMessage(err error) string {
+	userErrorMessage := message("retrieve-error") + "\n"
+
+	var apiError *googleapi.Error
+	var dnsError *net.DNSError
+	var opError *net.OpError
+	var retrieveError *oauth2.RetrieveError
+	var urlError *url.Error
+	switch {
+	case errors.As(err, &dnsError), errors.As(err, &opError) && opError.Op == "dial":
+		userErrorMessage += message("offline")
+	case errors.As(err, &retrieveError):
+		userErrorMessage += message("access-expired")
+	case errors.As(err, &apiError):
+		switch {
+		case apiError.Code == http.StatusTooManyRequests || isRateLimitError(apiError):
+			userErrorMessage += message("rate-limited")
+		case apiError.Code == http.StatusUnauthorized || apiError.Code == http.StatusForbidden:
+			userErrorMessage += message("access-denied")
+		default:
+			userErrorMessage += apiError.Message
+		}
+	case errors.As(err, &urlError):
+		if urlError.Timeout() {
+			userErrorMessage += message("timeout")
+		} else {
+			userErrorMessage += urlError.Err.Error()
+		}
+	default:
+		userErrorMessage += err.Error()
+	}
+
+	return userErrorMessage
+}
+
+// Gets how long to wait before retrying if the error is Google Calendar rate limiting requests. The Retry-After header
+// is used if present, either in seconds or as a date
+func rateLimitDelay(err error) (time.Duration, bool) {
+	var apiError *googleapi.Error
+	if !errors.As(err, &apiError) || (apiError.Code != http.StatusTooManyRequests && !isRateLimitError(apiError)) {
+		return 0, false
+	}
+
+	retryAfter := apiError.Header.Get("Retry-After")
+	if seconds, err := strconv.Atoi(retryAfter); err == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(retryAfter); err == nil && date.After(time.Now()) {
+		return time.Until(date), true
+	}
 
-		var responseIcon *widget.Icon
-		switch event.response {
-		case needsAction:
-			responseIcon = widget.NewIcon(ui.ResourceWarningPng)
-		case declined:
-			responseIcon = widget.NewIcon(ui.ResourceCancelPng)
-		case tentative:
-			responseIcon = widget.NewIcon(ui.ResourceQuestionPng)
-		case accepted, empty:
-			responseIcon = widget.NewIcon(ui.ResourceCheckedPng)
+	return defaultRateLimitDelay, true
+}
+
+func isRateLimitError(apiError *googleapi.Error) bool {
+	for _, item := range apiError.Errors {
+		if item.Reason == "rateLimitExceeded" || item.Reason == "userRateLimitExceeded" {
+			return true
 		}
+	}
+
+	return false
+}
 
-		title := ui.NewClickableText(eventText, eventStyle, eventColour)
-		details := widget.TextSegment{
-			Text: event.details,
+// Asks the user to connect to Google Calendar again, like when their token was granted before a scope that is now
+// needed
+func promptReconnect() {
+	runOnUi(func() {
+		parent := mainWindow
+		if parent == nil {
+			parent = settingsWindow
 		}
-		var buttons []*widget.Button
-		if strings.HasPrefix(event.location, "https://") || strings.HasPrefix(event.location, "http://") {
-			locationUrl, err := url.Parse(event.location)
-			if err == nil {
-				meetingButton := widget.NewButtonWithIcon("", theme.MediaVideoIcon(), func() { dailyApp.OpenURL(locationUrl) })
-				if event.isFinished() {
-					meetingButton.Disable()
-				}
-				buttons = append(buttons, meetingButton)
-			}
+		if parent == nil {
+			return
 		}
+		dialog.ShowInformation(message("reconnect"), message("reconnect-scopes"), parent)
+	})
+}
 
-		eventsList.Add(ui.NewEvent(responseIcon, title, buttons, widget.NewRichText(&details)))
-	}
-
-	eventsList.Refresh()
+// Runs a UI call made by background work, like showing the result of a request that waited for the network. Fyne 2.5
+// can't run code on its own goroutine, so the calls go through a single goroutine that runs them in order instead of
+// racing each other
+func runOnUi(call func()) {
+	startUiCalls.Do(func() {
+		go func() {
+			for call := range uiCalls {
+				call()
+			}
+		}()
+	})
+	uiCalls <- call
 }
 
 func reportUserError(errorMessage string) {
+	if lastErrorButton == nil {
+		// the main window will refresh when it is built
+		return
+	}
+
 	if errorMessage != "" {
 		slog.Info("Reporting user error: " + errorMessage)
 		lastErrorButton.Hidden = false
 		lastErrorButton.OnTapped = func() {
-			dialog.ShowError(errors.New(errorMessage), dailyApp.Driver().AllWindows()[0])
+			dialog.ShowError(errors.New(errorMessage), mainWindow)
 		}
 	} else {
 		slog.Info("Clearing last user error")
@@ -240,10 +1378,14 @@ func reportUserError(errorMessage string) {
 }
 
 func showNoEvents() {
-	noEventsLabel := widget.NewLabel("No events today")
-	eventsList.Add(layout.NewSpacer())
-	eventsList.Add(container.NewCenter(noEventsLabel))
-	eventsList.Add(layout.NewSpacer())
+	if eventsList == nil {
+		return
+	}
+
+	noEventsLabel := widget.NewLabel(message("no-events"))
+	renderedEvents = make(map[string]*ui.Event)
+	eventsList.Objects = []fyne.CanvasObject{layout.NewSpacer(), container.NewCenter(noEventsLabel), layout.NewSpacer()}
+	eventsList.Refresh()
 }
 
 func createUserFriendlyDurationText(durationRemaining time.Duration) string {
@@ -261,52 +1403,337 @@ func createUserFriendlyDurationText(durationRemaining time.Duration) string {
 	return result
 }
 
-func notify(event *event, timeToStart time.Duration) {
+func notify(event *event, timeToStart time.Duration, notificationKey string) {
+	if isNotificationsSnoozed() {
+		slog.Debug("Not notifying for '" + event.title + "' because notifications are snoozed")
+		return
+	}
+	if isDoNotDisturb() {
+		slog.Debug("Not notifying for '" + event.title + "' because do not disturb is on")
+		return
+	}
+
 	slog.Debug("Sending notification for '" + event.title + "'. Time to start: " + timeToStart.String())
 	remaining := int(timeToStart.Round(time.Minute).Minutes())
-	notifTitle := "'" + event.title + "' is starting soon"
-	notifBody := strconv.Itoa(remaining) + " minutes to event"
+	notifTitle := fmt.Sprintf(message("starting-soon"), event.title)
+	notifBody := fmt.Sprintf(message("minutes-to-event"), remaining)
 	if remaining == 1 {
-		notifBody = strconv.Itoa(remaining) + " minute to event"
+		notifBody = fmt.Sprintf(message("minute-to-event"), remaining)
 	} else if remaining <= 0 {
-		notifTitle = "'" + event.title + "' is starting now"
+		notifTitle = fmt.Sprintf(message("starting-now"), event.title)
 	}
 	notification := fyne.NewNotification(notifTitle, notifBody)
-	dailyApp.SendNotification(notification)
-	event.notifiable = false
+	actions := notificationActions{}
+	if event.isVirtualMeeting() {
+		actions.meetingLink = event.location
+	}
+	snoozed := *event
+	actions.snooze = func() { snoozeReminder(snoozed, notificationKey) }
+	actions.show = func() { showNotifiedEvent(snoozed) }
+	sendNotification(notification, actions)
+	markNotified(notificationKey)
+}
+
+// Notifies about the event again after the minutes in the notification-snooze-minutes preference
+func snoozeReminder(snoozed event, notificationKey string) {
+	delay := time.Duration(snoozeReminderMinutes()) * time.Minute
+	slog.Debug("Notifying again about '" + snoozed.title + "' in " + delay.String())
+	time.AfterFunc(delay, func() { notifySnoozed(snoozed, notificationKey) })
+}
+
+// Notifies about a snoozed reminder unless the event finished. It goes through notify like any other reminder, so it is
+// not shown while all notifications are snoozed or do not disturb is on
+func notifySnoozed(snoozed event, notificationKey string) {
+	if snoozed.isFinished() {
+		slog.Debug("Not notifying again about '" + snoozed.title + "' because it finished")
+		return
+	}
+	notify(&snoozed, snoozed.start.Sub(now()), notificationKey)
+}
+
+func snoozeReminderMinutes() int {
+	return max(dailyApp.Preferences().IntWithFallback("notification-snooze-minutes", 5), 1)
+}
+
+func snoozeNotifications(until time.Time) {
+	slog.Info("Snoozing notifications until " + until.Format(time.RFC3339))
+	dailyApp.Preferences().SetString("notifications-snoozed-until", until.Format(time.RFC3339))
+	updateSnoozeIndicator()
+}
+
+func unsnoozeNotifications() {
+	slog.Info("Resuming notifications")
+	dailyApp.Preferences().RemoveValue("notifications-snoozed-until")
+	updateSnoozeIndicator()
+}
+
+func isNotificationsSnoozed() bool {
+	until, err := time.Parse(time.RFC3339, dailyApp.Preferences().String("notifications-snoozed-until"))
+	return err == nil && now().Before(until)
+}
+
+func updateSnoozeIndicator() {
+	tooltip := "Daily"
+	snoozed := isNotificationsSnoozed()
+	if snoozed {
+		until, _ := time.Parse(time.RFC3339, dailyApp.Preferences().String("notifications-snoozed-until"))
+		tooltip += " - " + message("snoozed-until") + until.Format("Mon 3:04PM")
+	}
+	if snoozeButton != nil && snoozed {
+		snoozeButton.Show()
+	} else if snoozeButton != nil {
+		snoozeButton.Hide()
+	}
+
+	if _, ok := dailyApp.(desktop.App); ok {
+		systray.SetTooltip(tooltip)
+	}
+}
+
+// Opens the url in the browser or, if that's not possible, copies it to the clipboard
+func openUrl(link *url.URL) {
+	err := dailyApp.OpenURL(link)
+	if err != nil {
+		parent := mainWindow
+		if parent == nil {
+			parent = settingsWindow
+		}
+		if parent == nil {
+			slog.Warn("Could not open url "+link.String(), "error", err)
+			return
+		}
+		slog.Warn("Could not open url. Copying it to the clipboard instead", "error", err)
+		parent.Clipboard().SetContent(link.String())
+		dialog.ShowInformation(message("no-browser"), message("link-copied"), parent)
+	}
+}
+
+// Shows a url for the user to open manually when there is no browser available. The user can then paste the resulting
+// authorization code, which is passed to onCodePasted. onClosed is called when the dialog closes, either after the code
+// was accepted or because the user gave up
+func showManualUrl(link string, parent fyne.Window, onCodePasted func(string) error, onClosed func()) {
+	linkEntry := widget.NewEntry()
+	linkEntry.SetText(link)
+	linkEntry.Wrapping = fyne.TextWrapBreak
+	copyButton := widget.NewButtonWithIcon(message("copy"), theme.ContentCopyIcon(), func() {
+		parent.Clipboard().SetContent(link)
+	})
+
+	codeEntry := widget.NewEntry()
+	codeEntry.SetPlaceHolder(message("redirect-placeholder"))
+	var manualDialog dialog.Dialog
+	submitButton := widget.NewButton(message("submit"), func() {
+		go func() {
+			err := onCodePasted(codeEntry.Text)
+			runOnUi(func() {
+				if err != nil {
+					dialog.ShowError(err, parent)
+					return
+				}
+				manualDialog.Hide()
+			})
+		}()
+	})
+
+	content := container.NewVBox(
+		widget.NewLabel(message("open-url-in-browser")),
+		linkEntry,
+		copyButton,
+		widget.NewLabel(message("paste-result")),
+		container.NewBorder(nil, nil, nil, submitButton, codeEntry),
+	)
+	manualDialog = dialog.NewCustom(message("open-url-manually"), message("close"), content, parent)
+	manualDialog.SetOnClosed(onClosed)
+	manualDialog.Show()
 }
 
 func showSettings(dailyApp fyne.App) {
 	slog.Info("Opening settings panel")
 
-	settingsWindow := dailyApp.NewWindow("Settings")
+	if settingsWindow != nil {
+		settingsWindow.RequestFocus()
+		return
+	}
+
+	settingsWindow = dailyApp.NewWindow(message("settings"))
+	settingsWindow.SetOnClosed(func() {
+		settingsWindow = nil
+	})
 	settingsWindow.Resize(fyne.NewSize(400, 200))
-	calendarIdLabel := widget.NewLabel("Calendar ID:")
+	calendarIdLabel := widget.NewLabel(message("calendar-id"))
 	calendarIdBox := widget.NewEntry()
-	calendarIdBox.Text = "primary"
-	var gCalToken string
-	connectButton := widget.NewButtonWithIcon("Google Calendar", ui.ResourceGoogleCalendarPng, func() {
-		var err error
-		gCalToken, err = startGCalOAuthFlow()
-		if err != nil {
-			dialog.ShowError(err, settingsWindow)
-			return
-		}
+	calendarIdBox.Text = dailyApp.Preferences().StringWithFallback("calendar-id", "primary")
+	// once connected, the calendars can also be picked by name. IDs can still be typed, like when they can't be listed
+	calendarChecks := widget.NewCheckGroup(nil, nil)
+	calendarChecks.Horizontal = true
+	calendarChecks.Hide()
+	window := settingsWindow
+	var gCalToken lockedString
+	connect := func(manual bool) {
+		// the flow waits for the browser so it can't block the UI
+		go func() {
+			token, err := startGCalOAuthFlow(window, manual)
+			if errors.Is(err, errOAuthCancelled) {
+				return
+			}
+			if err != nil {
+				runOnUi(func() { dialog.ShowError(err, window) })
+				return
+			}
+			gCalToken.set(token)
+			loadCalendarOptions(token, calendarChecks, calendarIdBox)
+		}()
+	}
+	connectButton := widget.NewButtonWithIcon("Google Calendar", ui.ResourceGoogleCalendarPng, func() { connect(false) })
+	var outlookToken lockedString
+	outlookButton := widget.NewButton("Outlook", func() {
+		go func() {
+			token, err := startOutlookOAuthFlow(window, false)
+			if errors.Is(err, errOAuthCancelled) {
+				return
+			}
+			if err != nil {
+				runOnUi(func() { dialog.ShowError(err, window) })
+				return
+			}
+			outlookToken.set(token)
+		}()
+	})
+	manualConnectButton := widget.NewButton(message("connect-manually"), func() { connect(true) })
+	manualConnectButton.Importance = widget.LowImportance
+
+	connectBox := container.NewHBox(connectButton, manualConnectButton, outlookButton, calendarIdLabel, calendarIdBox)
+
+	icsUrlBox := widget.NewEntry()
+	icsUrlBox.SetPlaceHolder(message("ics-url-placeholder"))
+	icsUrlBox.Text = dailyApp.Preferences().String("ics-url")
+
+	proxyUrlBox := widget.NewEntry()
+	proxyUrlBox.SetPlaceHolder(message("proxy-url-placeholder"))
+	proxyUrlBox.Text = dailyApp.Preferences().String("proxy-url")
+
+	slackEnabledCheck := widget.NewCheck(message("slack-enabled"), nil)
+	slackEnabledCheck.Checked = dailyApp.Preferences().BoolWithFallback("slack-enabled", true)
+	slackTokenBox := widget.NewPasswordEntry()
+	slackTokenBox.Text = dailyApp.Preferences().String("slack-token")
+	var slackTestButton *widget.Button
+	slackTestButton = widget.NewButton(message("test-connection"), func() {
+		// one test at a time, so the results don't pile up
+		slackTestButton.Disable()
+		token := strings.TrimSpace(slackTokenBox.Text)
+		go func() {
+			result, err := testSlackConnection(token)
+			runOnUi(func() {
+				slackTestButton.Enable()
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				dialog.ShowInformation(message("slack-test-title"), result, window)
+			})
+		}()
 	})
 
-	connectBox := container.NewHBox(connectButton, calendarIdLabel, calendarIdBox)
+	accentColor := dailyApp.Preferences().String("accent-color")
+	accentLabel := widget.NewLabel("")
+	updateAccentLabel := func() {
+		if accentColor == "" {
+			accentLabel.SetText(message("default-color"))
+		} else {
+			accentLabel.SetText(accentColor)
+		}
+	}
+	updateAccentLabel()
+	accentButton := widget.NewButtonWithIcon(message("accent-color"), theme.ColorPaletteIcon(), func() {
+		picker := dialog.NewColorPicker(message("accent-color"), "", func(picked color.Color) {
+			accentColor = formatHexColor(picked)
+			updateAccentLabel()
+		}, settingsWindow)
+		picker.Advanced = true
+		if current, err := parseHexColor(accentColor); err == nil {
+			picker.SetColor(current)
+		}
+		picker.Show()
+	})
+	resetAccentButton := widget.NewButtonWithIcon("", theme.ContentUndoIcon(), func() {
+		accentColor = ""
+		updateAccentLabel()
+	})
+	resetAccentButton.Importance = widget.LowImportance
 
-	saveButton := widget.NewButton("Save", func() {
-		dailyApp.Preferences().SetString("calendar-token", gCalToken)
+	saveSettings := func(newGCalToken string, newOutlookToken string, previousSource []string) {
+		// the Google calendar is used when both are connected, so connecting to Outlook replaces it
+		if newOutlookToken != "" {
+			dailyApp.Preferences().SetString("outlook-token", newOutlookToken)
+			dailyApp.Preferences().RemoveValue("calendar-token")
+		}
+		if newGCalToken != "" {
+			dailyApp.Preferences().SetString("calendar-token", newGCalToken)
+		}
 		dailyApp.Preferences().SetString("calendar-id", calendarIdBox.Text)
+		dailyApp.Preferences().SetString("ics-url", strings.TrimSpace(icsUrlBox.Text))
+		dailyApp.Preferences().SetBool("slack-enabled", slackEnabledCheck.Checked)
+		dailyApp.Preferences().SetString("slack-token", strings.TrimSpace(slackTokenBox.Text))
+		dailyApp.Preferences().SetString("accent-color", accentColor)
+		applyAccentColor()
 		slog.Info("Preferences saved")
-		settingsWindow.Close()
+		resetEventSource(previousSource)
+		window.Close()
+	}
+
+	var saveButton *widget.Button
+	saveButton = widget.NewButton(message("save"), func() {
+		previousSource := sourcePreferences()
+		// the proxy might be needed to validate the calendar
+		dailyApp.Preferences().SetString("proxy-url", strings.TrimSpace(proxyUrlBox.Text))
+		// tokens are only replaced when the user connected again, so saving other settings doesn't disconnect them
+		newGCalToken, newOutlookToken := gCalToken.get(), outlookToken.get()
+		validationToken := newGCalToken
+		if validationToken == "" && calendarIdBox.Text != dailyApp.Preferences().String("calendar-id") {
+			validationToken = dailyApp.Preferences().String("calendar-token")
+		}
+		if validationToken == "" || *testCalendar {
+			saveSettings(newGCalToken, newOutlookToken, previousSource)
+			return
+		}
+
+		// validating reaches Google Calendar so it can't block the UI
+		saveButton.Disable()
+		progress := dialog.NewCustomWithoutButtons(message("validating-calendar"), widget.NewProgressBarInfinite(), window)
+		progress.Show()
+		calendarId := calendarIdBox.Text
+		go func() {
+			err := validateCalendarId(validationToken, calendarId)
+			runOnUi(func() {
+				if settingsWindow != window {
+					// the settings were closed without waiting for the validation
+					return
+				}
+				progress.Hide()
+				saveButton.Enable()
+				if err != nil {
+					dialog.ShowError(err, window)
+					return
+				}
+				saveSettings(newGCalToken, newOutlookToken, previousSource)
+			})
+		}()
 	})
 
+	configFolderButton := widget.NewButtonWithIcon(message("open-config-folder"), theme.FolderOpenIcon(), openConfigFolder)
+	configFolderButton.Importance = widget.LowImportance
+
 	content := container.NewVBox(
-		widget.NewLabel("Connect to"),
+		widget.NewLabel(message("connect-to")),
 		connectBox,
+		calendarChecks,
+		container.NewBorder(nil, nil, widget.NewLabel(message("ics-url")), nil, icsUrlBox),
+		container.NewBorder(nil, nil, widget.NewLabel(message("proxy-url")), nil, proxyUrlBox),
+		slackEnabledCheck,
+		container.NewBorder(nil, nil, widget.NewLabel(message("slack-token")), slackTestButton, slackTokenBox),
+		container.NewHBox(accentButton, accentLabel, resetAccentButton),
 		layout.NewSpacer(),
+		container.NewHBox(configFolderButton, layout.NewSpacer()),
 		saveButton,
 	)
 
@@ -314,13 +1741,182 @@ func showSettings(dailyApp fyne.App) {
 	settingsWindow.Show()
 }
 
-func changeDay(newDate time.Time, dayLabel *widget.Label) {
+// Gets the preferences the event source is created with
+func sourcePreferences() []string {
+	preferences := dailyApp.Preferences()
+	return []string{preferences.String("calendar-token"), preferences.String("calendar-id"), preferences.String("outlook-token"), preferences.String("ics-url"), preferences.String("proxy-url")}
+}
+
+// Discards the event source and the cached events if the preferences the source is created with changed from the
+// previous ones, so that the next refresh reconnects with the new settings. Saving other settings keeps the events
+func resetEventSource(previous []string) {
+	if slices.Equal(previous, sourcePreferences()) {
+		return
+	}
+
+	slog.Debug("Event source settings changed")
+	eventsMutex.Lock()
+	eventSource = nil
+	eventsMutex.Unlock()
+	clearEventsCache()
+}
+
+// Shows the calendars of the account to pick the ones in the calendar ID entry, which is what gets saved. The entry can
+// still be edited, and the calendars are not shown if they can't be retrieved
+func loadCalendarOptions(token string, calendarChecks *widget.CheckGroup, calendarIdBox *widget.Entry) {
+	if *testCalendar {
+		return
+	}
+
+	service, err := newCalendarService(token)
+	if err != nil {
+		return
+	}
+	entries, err := listCalendars(service)
+	if err != nil || len(entries) == 0 {
+		slog.Warn("Could not list the calendars. Keeping the calendar ID entry only", "error", err)
+		return
+	}
+
+	names, ids := createCalendarOptions(entries)
+	runOnUi(func() {
+		calendarChecks.Options = names
+		calendarChecks.Selected = findPickedCalendars(calendarIdBox.Text, names, ids)
+		calendarChecks.OnChanged = func(picked []string) {
+			calendarIdBox.SetText(pickCalendars(calendarIdBox.Text, names, ids, picked))
+		}
+		// setting the checks directly doesn't call OnChanged, which would change the entry back
+		calendarIdBox.OnChanged = func(calendarIds string) {
+			calendarChecks.Selected = findPickedCalendars(calendarIds, names, ids)
+			calendarChecks.Refresh()
+		}
+		calendarChecks.Show()
+		calendarChecks.Refresh()
+	})
+}
+
+// Shows a dialog to search events by title and details. Only the displayed day is searched unless the
+// search-all-days preference is set, in which case all the buffered events are searched and the matches are grouped by
+// day. Tapping a match navigates to its day
+func showSearch() {
+	if currentEventSource() == nil {
+		return
+	}
+
+	searchAllDays := dailyApp.Preferences().Bool("search-all-days")
+	results := container.NewVBox()
+	var searchDialog dialog.Dialog
+	queryEntry := widget.NewEntry()
+	queryEntry.SetPlaceHolder(message("search-placeholder"))
+	queryEntry.OnSubmitted = func(query string) {
+		var candidates []event
+		events, _ := getBufferedEvents()
+		for _, event := range events {
+			if searchAllDays || isOnSameDay(event.start, displayDay) {
+				candidates = append(candidates, event)
+			}
+		}
+
+		results.RemoveAll()
+		matches := searchEvents(candidates, query)
+		if len(matches) == 0 {
+			results.Add(widget.NewLabel(message("no-matches")))
+		}
+		for pos, match := range matches {
+			if pos == 0 || !isOnSameDay(matches[pos-1].start, match.start) {
+				results.Add(widget.NewLabelWithStyle(match.start.Format(dayFormat), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+			}
+			day := match.start
+			matchButton := widget.NewButton(match.start.Format("3:04PM ")+match.title, func() {
+				searchDialog.Hide()
+				changeDay(day)
+			})
+			matchButton.Alignment = widget.ButtonAlignLeading
+			matchButton.Importance = widget.LowImportance
+			results.Add(matchButton)
+		}
+	}
+
+	content := container.NewBorder(queryEntry, nil, nil, nil, container.NewVScroll(results))
+	searchDialog = dialog.NewCustom(message("search"), message("close"), content, mainWindow)
+	searchDialog.Resize(fyne.NewSize(350, 400))
+	searchDialog.Show()
+	mainWindow.Canvas().Focus(queryEntry)
+}
+
+// Gets the events whose title or details contain the query, ignoring case
+func searchEvents(events []event, query string) []event {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil
+	}
+
+	var result []event
+	for _, event := range events {
+		if strings.Contains(strings.ToLower(event.title), query) || strings.Contains(strings.ToLower(cleanEventDetails(event.details)), query) {
+			result = append(result, event)
+		}
+	}
+
+	return result
+}
+
+// Opens the folder where the preferences and any other app files are stored, to help troubleshooting
+func openConfigFolder() {
+	folder := dailyApp.Storage().RootURI()
+	slog.Info("Opening config folder " + folder.String())
+	folderUrl, err := url.Parse(folder.String())
+	if err != nil {
+		slog.Error("Invalid config folder", "error", err)
+		return
+	}
+	openUrl(folderUrl)
+}
+
+func changeDay(newDate time.Time) {
+	if !isWithinNavigationLimit(newDate) {
+		slog.Info("Not changing day to " + newDate.Format(dayFormat) + ". It is beyond the navigation limit")
+		return
+	}
+
 	slog.Info("Changing day to " + newDate.Format(dayFormat))
 	displayDay = newDate
-	dayLabel.SetText(displayDay.Format(dayFormat))
+	if dayLabel != nil {
+		dayLabel.SetText(displayedPeriod())
+	}
+	updateNavigationButtons()
 	refresh(false)
 }
 
+// Whether the day is at most navigation-limit-days away from today. A limit of 0 or less allows any day
+func isWithinNavigationLimit(day time.Time) bool {
+	limit := dailyApp.Preferences().IntWithFallback("navigation-limit-days", 60)
+	if limit <= 0 {
+		return true
+	}
+
+	today := startOfDay(now())
+	target := startOfDay(day)
+	return !target.Before(today.AddDate(0, 0, -limit)) && !target.After(today.AddDate(0, 0, limit))
+}
+
+// Disables the buttons to go to the previous or next day, or week in week view, when it is beyond the navigation limit
+func updateNavigationButtons() {
+	if previousDay == nil {
+		return
+	}
+
+	update := func(button *widget.Button, days int) {
+		if isWithinNavigationLimit(displayDay.AddDate(0, 0, days*navigationStep())) {
+			button.Enable()
+		} else {
+			button.Disable()
+		}
+	}
+	update(previousDay, -1)
+	update(nextDay, 1)
+}
+
 func isOnSameDay(one time.Time, other time.Time) bool {
 	year1, month1, day1 := one.Date()
 	year2, month2, day2 := other.Date()
@@ -328,15 +1924,84 @@ func isOnSameDay(one time.Time, other time.Time) bool {
 }
 
 type event struct {
-	title      string
-	start      time.Time
-	end        time.Time
-	location   string
-	details    string
-	notifiable bool
-	response   responseStatus
+	id          string
+	title       string
+	start       time.Time
+	end         time.Time
+	location    string
+	details     string
+	notifiable  bool
+	response    responseStatus
+	attachments []attachment
+	htmlLink    string
+	updated     time.Time
+	source      string
+	kind        sourceKind
+	calendarId  string
+	organizer   bool
+	recurring   bool
+	dimmed      bool
+	tags        []string
+	dialIn      dialIn
+	// Minutes before the start to notify at. Empty to use the notification-time preference
+	reminders []int
+	// Whether the event is shown as free in the calendar, so it doesn't make the user busy
+	free bool
+}
+
+// The ways to join a meeting by video or phone
+type dialIn struct {
+	url   string
+	phone string
+	pin   string
+}
+
+// Finds a phone number and a PIN in the details of an event. Numbers without a country code must have the area code
+// separated, like (415) 555-0100 or 1-855-555-0100, so that meeting IDs aren't taken for them
+func parseDialIn(details string) dialIn {
+	result := dialIn{phone: phoneRegex.FindString(details)}
+	if match := pinRegex.FindStringSubmatch(details); match != nil {
+		result.pin = match[1]
+	}
+
+	return result
+}
+
+func (info dialIn) isEmpty() bool {
+	return info.phone == "" && info.url == ""
+}
+
+// Formats the dial-in information so it can be pasted somewhere else
+func (info dialIn) clipboardText() string {
+	var lines []string
+	if info.url != "" {
+		lines = append(lines, "Join: "+info.url)
+	}
+	if info.phone != "" {
+		dial := "Dial: " + info.phone
+		if info.pin != "" {
+			dial += " PIN: " + info.pin + "#"
+		}
+		lines = append(lines, dial)
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+type attachment struct {
+	title string
+	url   string
 }
 
+// The kind of calendar an event comes from, which decides how it is opened in the browser
+type sourceKind string
+
+const (
+	googleSource  sourceKind = "google"
+	outlookSource sourceKind = "outlook"
+	icsSource     sourceKind = "ics"
+)
+
 type responseStatus string
 
 const (
@@ -347,20 +2012,114 @@ const (
 	accepted    responseStatus = "accepted"
 )
 
+func (otherEvent *event) responseIcon() fyne.Resource {
+	switch otherEvent.response {
+	case needsAction:
+		return ui.ResourceWarningPng
+	case declined:
+		return ui.ResourceCancelPng
+	case tentative:
+		return ui.ResourceQuestionPng
+	default:
+		return ui.ResourceCheckedPng
+	}
+}
+
+// Gets the icon of the button that opens the event in the browser. Only Google Calendar events get its logo
+func (otherEvent *event) browserIcon() fyne.Resource {
+	if otherEvent.kind == googleSource {
+		return ui.ResourceGoogleCalendarPng
+	}
+	return theme.ComputerIcon()
+}
+
+// Gets the minutes before the start to notify at, using the event's own reminders if it has any. The result can be
+// modified without affecting the event
+func (otherEvent *event) reminderTimes(defaultReminders []int) []int {
+	if len(otherEvent.reminders) == 0 {
+		return slices.Clone(defaultReminders)
+	}
+
+	return slices.Clone(otherEvent.reminders)
+}
+
+// Identifies a version of an event so that its rendering can be reused while the event doesn't change
+func (otherEvent *event) renderKey() string {
+	return otherEvent.id + "@" + otherEvent.updated.Format(time.RFC3339Nano)
+}
+
+func (otherEvent *event) isVirtualMeeting() bool {
+	return otherEvent.isVideoMeeting() || otherEvent.isAudioMeeting()
+}
+
+// Uses the first link of a known meeting provider in the details as the location, unless the location is already a way
+// to join. Invites often have the real link buried in the description and a room or just the provider's name as the
+// location
+func (otherEvent *event) useMeetingLinkInDetails() {
+	if otherEvent.isVirtualMeeting() {
+		return
+	}
+	if link := findMeetingLink(otherEvent.details); link != "" {
+		otherEvent.location = link
+	}
+}
+
+func (otherEvent *event) isVideoMeeting() bool {
+	return strings.HasPrefix(otherEvent.location, "https://") || strings.HasPrefix(otherEvent.location, "http://")
+}
+
+// Whether the meeting can only be joined via SIP or phone
+func (otherEvent *event) isAudioMeeting() bool {
+	return strings.HasPrefix(otherEvent.location, "sip:") || strings.HasPrefix(otherEvent.location, "tel:")
+}
+
+func (otherEvent *event) meetingIcon() fyne.Resource {
+	if otherEvent.isAudioMeeting() {
+		return theme.VolumeUpIcon()
+	}
+	return theme.MediaVideoIcon()
+}
+
+// Whether the user is busy during the event. Declined and dimmed events don't make them busy, and neither do events
+// shown as free unless the free-events-busy preference is set
+func (otherEvent *event) isBusy() bool {
+	if otherEvent.free && !dailyApp.Preferences().Bool("free-events-busy") {
+		return false
+	}
+	return otherEvent.response != declined && !otherEvent.dimmed
+}
+
 func (otherEvent *event) isFinished() bool {
-	return otherEvent.end.Before(time.Now())
+	return otherEvent.end.Before(now())
 }
 
 func (otherEvent *event) isStarted() bool {
-	now := time.Now()
-	return otherEvent.start.Before(now) && otherEvent.end.After(now)
+	currentTime := now()
+	return otherEvent.start.Before(currentTime) && otherEvent.end.After(currentTime)
+}
+
+// Whether there is a Google Calendar or Outlook token or an iCalendar feed to get events from
+func isCalendarConfigured() bool {
+	preferences := dailyApp.Preferences()
+	return preferences.String("calendar-token") != "" || preferences.String("outlook-token") != "" || preferences.String("ics-url") != ""
 }
 
+// Gets the events to display from the event source, creating it if needed
 func getEvents(fullRefresh bool) ([]event, error) {
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
 	if eventSource == nil {
 		slog.Info("No event source found. Creating one")
 		if *testCalendar {
 			eventSource = newDummyEventSource()
+		} else if icsUrl := dailyApp.Preferences().String("ics-url"); icsUrl != "" {
+			eventSource = newIcsEventSource(icsUrl)
+		} else if dailyApp.Preferences().String("calendar-token") == "" && dailyApp.Preferences().String("outlook-token") != "" {
+			var err error
+			eventSource, err = newOutlookEventSource()
+			if err != nil {
+				return nil, err
+			}
 		} else {
 			var err error
 			eventSource, err = newGoogleCalendarEventSource()
@@ -377,14 +2136,52 @@ func getEvents(fullRefresh bool) ([]event, error) {
 	}
 
 	events, fullRefreshed, err := eventSource.getEvents(displayDay, fullRefresh)
+	if err == nil && isWeekView() {
+		// the day is still retrieved so that the buffer follows the displayed day like in day view
+		weekStart := startOfWeek(displayDay)
+		events, err = eventSource.getEventsBetween(weekStart, weekStart.AddDate(0, 0, 7))
+	}
 
 	if fullRefreshed {
 		lastFullRefresh = time.Now()
+		trackChanges(eventSource.getBufferedEvents())
 	}
 
 	return events, err
 }
 
+// Gets the event source, if there is one yet
+func currentEventSource() EventSource {
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
+
+	return eventSource
+}
+
+// Gets a copy of the buffered events of the event source, so that they can be used while a refresh replaces them.
+// Nothing is found if there is no event source yet
+func getBufferedEvents() ([]event, bool) {
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
+	if eventSource == nil {
+		return nil, false
+	}
+
+	return slices.Clone(eventSource.getBufferedEvents()), true
+}
+
+// Gets the events that start in the range from the event source. Nothing is found if there is no event source yet
+func getEventsBetween(start time.Time, end time.Time) ([]event, bool, error) {
+	eventsMutex.Lock()
+	defer eventsMutex.Unlock()
+	if eventSource == nil {
+		return nil, false, nil
+	}
+	events, err := eventSource.getEventsBetween(start, end)
+
+	return events, true, err
+}
+
 type dummyEventSource struct {
 	originalNow time.Time
 	yesterday   []event
@@ -393,26 +2190,53 @@ type dummyEventSource struct {
 }
 
 func newDummyEventSource() *dummyEventSource {
-	now := time.Now().Truncate(time.Minute)
-	start1 := now.Add(-3 * time.Hour)
+	current := now().Truncate(time.Minute)
+	start1 := current.Add(-3 * time.Hour)
 	end1 := start1.Add(30 * time.Minute)
-	return &dummyEventSource{
-		originalNow: now,
+	result := &dummyEventSource{
+		originalNow: current,
 		yesterday: []event{
-			{title: "past event yesterday with zoom", location: "http://www.zoom.us/1234", details: "Past event", start: start1.Add(-24 * time.Hour), end: time.Now().Add(-24*time.Hour + 30*time.Minute)},
+			{id: "dummy1", title: "past event yesterday with zoom", location: "http://www.zoom.us/1234", details: "Past event", start: start1.Add(-24 * time.Hour), end: current.Add(-24*time.Hour + 30*time.Minute)},
 		},
 		today: []event{
-			{title: "past event", location: "location1", details: "details1", start: start1, end: end1, response: accepted},
-			{title: "past event with zoom meeting", location: "http://www.zoom.us/1234", details: "detauls2", start: start1.Add(time.Hour), end: end1.Add(time.Hour), response: declined},
-			{title: "current event", location: "location3", details: "detauls3", start: now.Add(-10 * time.Minute), end: now.Add(30 * time.Minute), response: declined},
-			{title: "A very long current event with zoom meeting that is longer than the rest", location: "https://www.zoom.us/2345", details: "details4", start: now, end: now.Add(time.Hour), response: tentative},
-			{title: "future event today", location: "location5", details: "details5", start: now.Add(1 * time.Minute), end: time.Now().Add(6*time.Hour + 30*time.Minute), response: needsAction},
-			{title: "future event today with gmeeting", location: "https://meet.google.com/3456", details: "details6", start: now.Add(2 * time.Minute), end: time.Now().Add(7*time.Hour + 30*time.Minute), notifiable: true, response: accepted},
+			{id: "dummy2", title: "past event", location: "location1", details: "details1", start: start1, end: end1, response: accepted},
+			{id: "dummy3", title: "past event with zoom meeting", location: "http://www.zoom.us/1234", details: "detauls2", start: start1.Add(time.Hour), end: end1.Add(time.Hour), response: declined},
+			{id: "dummy4", title: "current event", location: "location3", details: "detauls3", start: current.Add(-10 * time.Minute), end: current.Add(30 * time.Minute), response: declined},
+			{id: "dummy5", title: "A very long current event with zoom meeting that is longer than the rest", location: "https://www.zoom.us/2345", details: "details4", start: current, end: current.Add(time.Hour), response: tentative},
+			{id: "dummy6", title: "future event today", recurring: true, location: "location5", details: "details5 #deepwork", start: current.Add(1 * time.Minute), end: current.Add(6*time.Hour + 30*time.Minute), response: needsAction},
+			{id: "dummy7", title: "future event today with gmeeting", location: "https://meet.google.com/3456", details: "details6", start: current.Add(2 * time.Minute), end: current.Add(7*time.Hour + 30*time.Minute), notifiable: true, response: accepted, organizer: true, dialIn: dialIn{url: "https://meet.google.com/3456", phone: "+1 555-0100", pin: "1234"}, attachments: []attachment{{title: "Agenda", url: "https://docs.google.com/document/d/1234"}}},
 		},
 		tomorrow: []event{
-			{title: "future event tomorrow with gmeeting", location: "https://meet.google.com/3456", details: "Future Event", start: start1.Add(24 * time.Hour), end: time.Now().Add(24*time.Hour + 30*time.Minute)},
+			{id: "dummy8", title: "future event tomorrow with gmeeting", location: "https://meet.google.com/3456", details: "Future Event", start: start1.Add(24 * time.Hour), end: current.Add(24*time.Hour + 30*time.Minute)},
 		},
 	}
+
+	for _, events := range [][]event{result.yesterday, result.today, result.tomorrow} {
+		for pos := range events {
+			events[pos].source = "Dummy calendar"
+			events[pos].tags = extractTags(events[pos].details)
+		}
+	}
+
+	return result
+}
+
+func (dummy dummyEventSource) getBufferedEvents() []event {
+	// yesterday's and tomorrow's events are not tied to a particular date
+	return dummy.today
+}
+
+func (dummy dummyEventSource) getEventsBetween(start time.Time, end time.Time) ([]event, error) {
+	var result []event
+	for _, events := range [][]event{dummy.yesterday, dummy.today, dummy.tomorrow} {
+		for _, event := range events {
+			if !event.start.Before(start) && event.start.Before(end) {
+				result = append(result, event)
+			}
+		}
+	}
+
+	return result, nil
 }
 
 func (dummy dummyEventSource) getEvents(day time.Time, fullRefresh bool) ([]event, bool, error) {
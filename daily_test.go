@@ -1,9 +1,23 @@
 package main
 
 import (
+	"errors"
+	"image/color"
+	"net/http"
+	"net/url"
+	"slices"
 	"strconv"
+	"strings"
 	"testing"
 	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"github.com/theHilikus/daily/internal/ui"
+	"google.golang.org/api/googleapi"
 )
 
 type durationTest struct {
@@ -36,3 +50,980 @@ func TestDurationText(t *testing.T) {
 		}
 	}
 }
+
+type detailsTest struct {
+	originalDetails string
+	expectedDetails string
+}
+
+func TestCleanEventDetails(t *testing.T) {
+	var detailsTests = []detailsTest{
+		{"plain text", "plain text"},
+		{"<p>first</p><p>second</p>", "first\nsecond"},
+		{"line1<br>line2<BR/>line3", "line1\nline2\nline3"},
+		{"<b>bold</b> &amp; <i>italic</i>", "bold & italic"},
+		{"<a href=\"https://example.com\">link</a>", "link (https://example.com)"},
+		{"<a href='https://zoom.us/j/1234'>https://zoom.us/j/1234</a>", "https://zoom.us/j/1234"},
+		{"<a href=\"mailto:bob@example.com\"><b>bob@example.com</b></a>", "bob@example.com"},
+		{"Agenda: <TBD>", "Agenda: <TBD>"},
+		{"a <b>b</b> < c", "a b < c"},
+		{"<p><br></p><p><br></p><p><br></p>text", "text"},
+		{"unclosed <tag", "unclosed <tag"},
+		{"", ""},
+	}
+
+	for i, test := range detailsTests {
+		if actual := cleanEventDetails(test.originalDetails); actual != test.expectedDetails {
+			t.Errorf("%d. Actual %q doesn't match expected %q. Original was %q", i, actual, test.expectedDetails, test.originalDetails)
+		}
+	}
+}
+
+type segmentsTest struct {
+	details        string
+	expectedTexts  []string
+	expectedEmails []string
+}
+
+func TestCreateDetailsSegments(t *testing.T) {
+	var segmentsTests = []segmentsTest{
+		{"no emails here", []string{"no emails here"}, nil},
+		{"contact bob@example.com for info", []string{"contact ", "bob@example.com", " for info"}, []string{"bob@example.com"}},
+		{"alice.smith+cal@mail.example.org", []string{"alice.smith+cal@mail.example.org"}, []string{"alice.smith+cal@mail.example.org"}},
+		{"write to mailto:bob@example.com", []string{"write to ", "bob@example.com"}, []string{"bob@example.com"}},
+		{"a@example.com, b@example.com", []string{"a@example.com", ", ", "b@example.com"}, []string{"a@example.com", "b@example.com"}},
+		{"not an email: bob@localhost", []string{"not an email: bob@localhost"}, nil},
+		{"", []string{""}, nil},
+	}
+
+	for i, test := range segmentsTests {
+		segments := createDetailsSegments(test.details)
+		if len(segments) != len(test.expectedTexts) {
+			t.Fatalf("%d. Actual %d segments don't match expected %d. Original was %q", i, len(segments), len(test.expectedTexts), test.details)
+		}
+		var emails []string
+		for j, segment := range segments {
+			var text string
+			switch s := segment.(type) {
+			case *widget.TextSegment:
+				text = s.Text
+			case *widget.HyperlinkSegment:
+				text = s.Text
+				if s.URL.String() != "mailto:"+s.Text {
+					t.Errorf("%d. Actual link %q doesn't match its text %q", i, s.URL.String(), s.Text)
+				}
+				emails = append(emails, s.Text)
+			}
+			if text != test.expectedTexts[j] {
+				t.Errorf("%d. Actual segment %q doesn't match expected %q. Original was %q", i, text, test.expectedTexts[j], test.details)
+			}
+		}
+		if len(emails) != len(test.expectedEmails) {
+			t.Errorf("%d. Actual emails %q don't match expected %q", i, emails, test.expectedEmails)
+		}
+	}
+}
+
+type defaultDurationTest struct {
+	defaultDuration int
+	expectedEnds    []time.Time
+}
+
+func TestApplyDefaultDuration(t *testing.T) {
+	start := time.Date(2024, 11, 20, 15, 0, 0, 0, time.Local)
+	events := []event{
+		{title: "marker", start: start, end: start},
+		{title: "meeting", start: start, end: start.Add(time.Hour)},
+	}
+	var defaultDurationTests = []defaultDurationTest{
+		{0, []time.Time{start, start.Add(time.Hour)}},
+		{15, []time.Time{start.Add(15 * time.Minute), start.Add(time.Hour)}},
+		{-1, []time.Time{start.Add(time.Hour)}},
+	}
+
+	for i, test := range defaultDurationTests {
+		actual := applyDefaultDuration(events, test.defaultDuration)
+		if len(actual) != len(test.expectedEnds) {
+			t.Fatalf("%d. Actual %d events don't match expected %d", i, len(actual), len(test.expectedEnds))
+		}
+		for j, expectedEnd := range test.expectedEnds {
+			if !actual[j].end.Equal(expectedEnd) {
+				t.Errorf("%d. Actual end %v of event %q doesn't match expected %v", i, actual[j].end, actual[j].title, expectedEnd)
+			}
+		}
+	}
+	if !events[0].end.Equal(start) {
+		t.Errorf("Original event was modified")
+	}
+}
+
+func TestRenderEventsUpdatesResponse(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	eventsList = container.NewVBox()
+	renderedEvents = map[string]*ui.Event{}
+
+	start := now().Add(time.Hour)
+	synced := event{id: "event1", title: "meeting", start: start, end: start.Add(time.Hour), response: needsAction}
+	renderEvents([]event{synced})
+	eventWidget := renderedEvents[synced.renderKey()]
+	if eventWidget.Icon.Resource != ui.ResourceWarningPng {
+		t.Fatalf("Actual icon %v doesn't match expected %v", eventWidget.Icon.Resource.Name(), ui.ResourceWarningPng.Name())
+	}
+
+	synced.response = accepted
+	renderEvents([]event{synced})
+	if renderedEvents[synced.renderKey()] != eventWidget {
+		t.Errorf("Row of unchanged event was not reused")
+	}
+	if eventWidget.Icon.Resource != ui.ResourceCheckedPng {
+		t.Errorf("Actual icon %v doesn't match expected %v after the response changed", eventWidget.Icon.Resource.Name(), ui.ResourceCheckedPng.Name())
+	}
+}
+
+func TestRenderEventsDetailMaxHeight(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	eventsList = container.NewVBox()
+	renderedEvents = map[string]*ui.Event{}
+
+	start := now().Add(time.Hour)
+	verbose := event{id: "event1", title: "meeting", start: start, end: start.Add(time.Hour), details: strings.Repeat("agenda item\n", 50)}
+	renderEvents([]event{verbose})
+	eventWidget := renderedEvents[verbose.renderKey()]
+	eventWidget.Open()
+	unlimitedHeight := eventWidget.MinSize().Height
+
+	dailyApp.Preferences().SetInt("detail-max-height", 100)
+	renderEvents([]event{verbose})
+	if cappedHeight := eventWidget.MinSize().Height; cappedHeight >= unlimitedHeight || cappedHeight > 200 {
+		t.Errorf("Actual height %v with a max detail height isn't capped (%v without it)", cappedHeight, unlimitedHeight)
+	}
+}
+
+type dueReminderTest struct {
+	reminders        []int
+	timeToStart      string
+	expectedReminder int
+	expectedDue      bool
+}
+
+func TestDueReminder(t *testing.T) {
+	var dueReminderTests = []dueReminderTest{
+		{[]int{1}, "5m", -1, false},
+		{[]int{1}, "1m", 1, true},
+		{[]int{1}, "30s", 1, true},
+		{[]int{30, 10}, "45m", -1, false},
+		{[]int{30, 10}, "29m", 30, true},
+		{[]int{30, 10}, "9m", 10, true},
+		{[]int{10, 30}, "9m", 10, true},
+		{[]int{0}, "1m", -1, false},
+		{[]int{0}, "0s", 0, true},
+	}
+
+	for i, test := range dueReminderTests {
+		timeToStart, err := time.ParseDuration(test.timeToStart)
+		if err != nil {
+			t.Fatal("Error parsing time to start " + strconv.Itoa(i))
+		}
+		reminder, due := dueReminder(test.reminders, timeToStart)
+		if reminder != test.expectedReminder || due != test.expectedDue {
+			t.Errorf("%d. Actual %d (due = %t) doesn't match expected %d (due = %t)", i, reminder, due, test.expectedReminder, test.expectedDue)
+		}
+	}
+}
+
+type searchTest struct {
+	query          string
+	expectedTitles []string
+}
+
+func TestSearchEvents(t *testing.T) {
+	events := []event{
+		{title: "Sync with Bob", details: "weekly"},
+		{title: "Planning", details: "<p>Agenda by <b>bob</b></p>"},
+		{title: "Lunch", details: "at the cafeteria"},
+	}
+	var searchTests = []searchTest{
+		{"bob", []string{"Sync with Bob", "Planning"}},
+		{"  LUNCH ", []string{"Lunch"}},
+		{"agenda by bob", []string{"Planning"}},
+		{"<b>", nil},
+		{"nobody", nil},
+		{"", nil},
+	}
+
+	for i, test := range searchTests {
+		var actual []string
+		for _, match := range searchEvents(events, test.query) {
+			actual = append(actual, match.title)
+		}
+		if !slices.Equal(actual, test.expectedTitles) {
+			t.Errorf("%d. Actual %q doesn't match expected %q", i, actual, test.expectedTitles)
+		}
+	}
+}
+
+type phoneTest struct {
+	details       string
+	expectedPhone string
+}
+
+func TestParseDialInPhone(t *testing.T) {
+	var phoneTests = []phoneTest{
+		{"Call +1 555-123-4567", "+1 555-123-4567"},
+		{"Dial 1-855-555-0100, PIN 1234", "1-855-555-0100"},
+		{"Phone: (415) 555-0100", "(415) 555-0100"},
+		{"Or 415.555.0100", "415.555.0100"},
+		{"Toll free 1 (800) 555-0199", "1 (800) 555-0199"},
+		{"Meeting ID: 812 3456 7890", ""},
+		{"Planning for 2024-11-18", ""},
+		{"", ""},
+	}
+
+	for i, test := range phoneTests {
+		if actual := parseDialIn(test.details).phone; actual != test.expectedPhone {
+			t.Errorf("%d. Actual phone %q doesn't match expected %q", i, actual, test.expectedPhone)
+		}
+	}
+}
+
+type dialInTextTest struct {
+	info         dialIn
+	expectedText string
+}
+
+func TestDialInClipboardText(t *testing.T) {
+	var dialInTextTests = []dialInTextTest{
+		{dialIn{}, ""},
+		{dialIn{url: "https://zoom.us/j/1234"}, "Join: https://zoom.us/j/1234"},
+		{dialIn{phone: "+1 555-1234"}, "Dial: +1 555-1234"},
+		{dialIn{url: "https://zoom.us/j/1234", phone: "+1 555-1234", pin: "1234"}, "Join: https://zoom.us/j/1234\nDial: +1 555-1234 PIN: 1234#"},
+	}
+
+	for i, test := range dialInTextTests {
+		if actual := test.info.clipboardText(); actual != test.expectedText {
+			t.Errorf("%d. Actual %q doesn't match expected %q", i, actual, test.expectedText)
+		}
+	}
+}
+
+func TestMeetingProgress(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	current := now()
+	ongoing := event{start: current.Add(-15 * time.Minute), end: current.Add(45 * time.Minute)}
+	future := event{start: current.Add(time.Hour), end: current.Add(2 * time.Hour)}
+	if actual := meetingProgress(&ongoing); actual >= 0 {
+		t.Errorf("Actual progress %f shown without the preference", actual)
+	}
+
+	dailyApp.Preferences().SetBool("show-meeting-progress", true)
+	if actual := meetingProgress(&ongoing); actual < 0.24 || actual > 0.26 {
+		t.Errorf("Actual progress %f doesn't match expected 0.25", actual)
+	}
+	if actual := meetingProgress(&future); actual >= 0 {
+		t.Errorf("Actual progress %f shown for a future event", actual)
+	}
+}
+
+type timeoutError struct{}
+
+func (timeoutError) Error() string   { return "timed out" }
+func (timeoutError) Timeout() bool   { return true }
+func (timeoutError) Temporary() bool { return true }
+
+type retrievalErrorTest struct {
+	err             error
+	expectedMessage string
+}
+
+func TestHandleEventRetrievalError(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	window := dailyApp.NewWindow("test")
+	window.Resize(fyne.NewSize(400, 600))
+	mainWindow = window
+	defer func() {
+		mainWindow = nil
+		lastErrorButton = nil
+	}()
+
+	prefix := message("retrieve-error") + "\n"
+	var retrievalErrorTests = []retrievalErrorTest{
+		{&googleapi.Error{Code: http.StatusInternalServerError, Message: "Backend Error"}, prefix + "Backend Error"},
+		{&googleapi.Error{Code: http.StatusTooManyRequests}, prefix + message("rate-limited")},
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, prefix + message("rate-limited")},
+		{&googleapi.Error{Code: http.StatusUnauthorized}, prefix + message("access-denied")},
+		{&url.Error{Op: "Get", URL: "https://www.googleapis.com", Err: timeoutError{}}, prefix + message("timeout")},
+		{&url.Error{Op: "Get", URL: "https://www.googleapis.com", Err: errors.New("connection reset")}, prefix + "connection reset"},
+		{errors.New("something failed"), prefix + "something failed"},
+	}
+
+	for i, test := range retrievalErrorTests {
+		if actual := createUserErrorMessage(test.err); actual != test.expectedMessage {
+			t.Errorf("%d. Actual %q doesn't match expected %q", i, actual, test.expectedMessage)
+		}
+
+		lastErrorButton = widget.NewButton("", nil)
+		lastErrorButton.Hidden = true
+		handleEventRetrievalError(test.err)
+		if lastErrorButton.Hidden {
+			t.Errorf("%d. Error button was not shown", i)
+		}
+		lastErrorButton.OnTapped()
+		errorDialog := window.Canvas().Overlays().Top()
+		if errorDialog == nil {
+			t.Fatalf("%d. No dialog shown after tapping the error button", i)
+		}
+		window.Canvas().Overlays().Remove(errorDialog)
+	}
+}
+
+func TestCreateEventTitleRecurringIndicator(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	start := time.Date(2024, 11, 20, 15, 0, 0, 0, time.Local)
+	recurring := event{title: "standup", start: start, end: start.Add(15 * time.Minute), recurring: true}
+	single := event{title: "review", start: start, end: start.Add(15 * time.Minute)}
+	if actual := createEventTitle(&recurring); actual != "3:00-3:15PM standup 🗘" {
+		t.Errorf("Actual %q doesn't have the recurring indicator", actual)
+	}
+	if actual := createEventTitle(&single); actual != "3:00-3:15PM review" {
+		t.Errorf("Actual %q has the recurring indicator for a single event", actual)
+	}
+
+	dailyApp.Preferences().SetBool("show-recurring-indicator", false)
+	if actual := createEventTitle(&recurring); actual != "3:00-3:15PM standup" {
+		t.Errorf("Actual %q has the recurring indicator when it is disabled", actual)
+	}
+}
+
+func TestCreateEventTitleRoundedTimes(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	start := time.Date(2024, 11, 20, 9, 2, 0, 0, time.Local)
+	imported := event{title: "sync", start: start, end: start.Add(56 * time.Minute)}
+	if actual := createEventTitle(&imported); actual != "9:02-9:58AM sync" {
+		t.Errorf("Actual %q doesn't have the original times", actual)
+	}
+
+	dailyApp.Preferences().SetInt("round-times-minutes", 15)
+	if actual := createEventTitle(&imported); actual != "9:00-10:00AM sync" {
+		t.Errorf("Actual %q doesn't have the rounded times", actual)
+	}
+	if !imported.start.Equal(start) {
+		t.Errorf("Actual start %v was modified", imported.start)
+	}
+}
+
+type roundTimeTest struct {
+	value    time.Time
+	minutes  int
+	expected time.Time
+}
+
+func TestRoundDisplayTime(t *testing.T) {
+	at := func(hour int, minute int) time.Time {
+		return time.Date(2024, 11, 20, hour, minute, 0, 0, time.Local)
+	}
+	var roundTimeTests = []roundTimeTest{
+		{at(9, 2), 0, at(9, 2)},
+		{at(9, 2), 1, at(9, 2)},
+		{at(9, 2), 5, at(9, 0)},
+		{at(9, 3), 5, at(9, 5)},
+		{at(9, 8), 15, at(9, 15)},
+		{at(9, 7), 15, at(9, 0)},
+		{at(23, 58), 5, at(24, 0)},
+	}
+
+	for i, test := range roundTimeTests {
+		if actual := roundDisplayTime(test.value, test.minutes); !actual.Equal(test.expected) {
+			t.Errorf("%d. Actual %v doesn't match expected %v", i, actual, test.expected)
+		}
+	}
+}
+
+func TestDefaultNotificationTimes(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	if actual := defaultNotificationTimes(); !slices.Equal(actual, []int{1}) {
+		t.Errorf("Actual %v doesn't match expected [1] without preferences", actual)
+	}
+	dailyApp.Preferences().SetInt("calendar-default-reminder", 10)
+	if actual := defaultNotificationTimes(); !slices.Equal(actual, []int{10}) {
+		t.Errorf("Actual %v doesn't match expected calendar default [10]", actual)
+	}
+	dailyApp.Preferences().SetInt("notification-time", 5)
+	if actual := defaultNotificationTimes(); !slices.Equal(actual, []int{5}) {
+		t.Errorf("Actual %v doesn't match expected user setting [5]", actual)
+	}
+	dailyApp.Preferences().SetString("notification-time", " 10, 1,x,10")
+	if actual := defaultNotificationTimes(); !slices.Equal(actual, []int{10, 1}) {
+		t.Errorf("Actual %v doesn't match expected user list [10 1]", actual)
+	}
+}
+
+func TestCheckNotificationsAtEachTime(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	defer func(original func() time.Time) { now = original }(now)
+	defer func(original string) { defaultNotificationBackend = original }(defaultNotificationBackend)
+	defaultNotificationBackend = "fyne"
+	defer func(original func() bool) { isDoNotDisturb = original }(isDoNotDisturb)
+	isDoNotDisturb = func() bool { return false }
+
+	dailyApp.Preferences().SetString("notification-time", "10,1")
+	currentTime := time.Date(2024, 11, 20, 9, 0, 0, 0, time.UTC)
+	now = func() time.Time { return currentTime }
+	events := []event{{id: "standup", title: "Standup", start: currentTime.Add(9 * time.Minute), end: currentTime.Add(time.Hour), notifiable: true}}
+
+	test.AssertNotificationSent(t, fyne.NewNotification("'Standup' is starting soon", "9 minutes to event"), func() {
+		checkNotifications(events)
+	})
+	test.AssertNotificationSent(t, nil, func() { checkNotifications(events) })
+	if !isNotified("standup@10") || isNotified("standup@1") {
+		t.Errorf("Actual notified reminders don't match expected only the 10 minute one")
+	}
+
+	currentTime = currentTime.Add(8 * time.Minute)
+	test.AssertNotificationSent(t, fyne.NewNotification("'Standup' is starting soon", "1 minute to event"), func() {
+		checkNotifications(events)
+	})
+	if !isNotified("standup@1") {
+		t.Errorf("The 1 minute reminder was not notified")
+	}
+}
+
+func TestCompileBlocklist(t *testing.T) {
+	blocklist, err := compileBlocklist([]string{`^\[HOLD\]`, `(unclosed`, `(?i)lunch`, `*invalid`})
+	if err == nil {
+		t.Errorf("Invalid patterns were not reported")
+	}
+	if len(blocklist) != 2 {
+		t.Errorf("Actual %d valid patterns don't match expected 2", len(blocklist))
+	}
+
+	blocklist, err = compileBlocklist(nil)
+	if err != nil || len(blocklist) != 0 {
+		t.Errorf("Actual %v (error %v) doesn't match expected empty blocklist", blocklist, err)
+	}
+}
+
+type blocklistTest struct {
+	dim            bool
+	expectedTitles []string
+	expectedDimmed []bool
+}
+
+func TestApplyBlocklist(t *testing.T) {
+	events := []event{{title: "[HOLD] focus"}, {title: "Planning"}, {title: "Team LUNCH"}}
+	blocklist, err := compileBlocklist([]string{`^\[HOLD\]`, `(?i)lunch`})
+	if err != nil {
+		t.Fatal("Error compiling blocklist", err)
+	}
+	var blocklistTests = []blocklistTest{
+		{false, []string{"Planning"}, []bool{false}},
+		{true, []string{"[HOLD] focus", "Planning", "Team LUNCH"}, []bool{true, false, true}},
+	}
+
+	for i, test := range blocklistTests {
+		actual := applyBlocklist(events, blocklist, test.dim)
+		if len(actual) != len(test.expectedTitles) {
+			t.Fatalf("%d. Actual %d events don't match expected %d", i, len(actual), len(test.expectedTitles))
+		}
+		for j := range actual {
+			if actual[j].title != test.expectedTitles[j] || actual[j].dimmed != test.expectedDimmed[j] {
+				t.Errorf("%d. Actual %q (dimmed = %t) doesn't match expected %q (dimmed = %t)", i, actual[j].title, actual[j].dimmed, test.expectedTitles[j], test.expectedDimmed[j])
+			}
+		}
+	}
+	if events[0].dimmed {
+		t.Errorf("Original event was modified")
+	}
+}
+
+type browserIconTest struct {
+	kind     sourceKind
+	expected fyne.Resource
+}
+
+func TestBrowserIcon(t *testing.T) {
+	var browserIconTests = []browserIconTest{
+		{googleSource, ui.ResourceGoogleCalendarPng},
+		{outlookSource, theme.ComputerIcon()},
+		{icsSource, theme.ComputerIcon()},
+	}
+	for i, test := range browserIconTests {
+		event := event{htmlLink: "https://example.com/event", kind: test.kind}
+		if actual := event.browserIcon(); actual.Name() != test.expected.Name() {
+			t.Errorf("%d. Actual icon %s doesn't match expected %s", i, actual.Name(), test.expected.Name())
+		}
+	}
+}
+
+type subtitleTest struct {
+	location         string
+	showSource       bool
+	expectedSubtitle string
+}
+
+func TestCreateEventSubtitle(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var subtitleTests = []subtitleTest{
+		{"", false, ""},
+		{"Room 3B", false, "Room 3B"},
+		{"https://meet.google.com/abc", false, ""},
+		{"tel:+1-555-1234", false, ""},
+		{"", true, "Work"},
+		{"Room 3B", true, "Room 3B · Work"},
+		{"https://meet.google.com/abc", true, "Work"},
+	}
+
+	for i, test := range subtitleTests {
+		dailyApp.Preferences().SetBool("show-event-source", test.showSource)
+		event := event{location: test.location, source: "Work"}
+		if actual := createEventSubtitle(&event); actual != test.expectedSubtitle {
+			t.Errorf("%d. Actual %q doesn't match expected %q", i, actual, test.expectedSubtitle)
+		}
+	}
+}
+
+type tagsTest struct {
+	details         string
+	expectedTags    []string
+	expectedDetails string
+}
+
+func TestTags(t *testing.T) {
+	var tagsTests = []tagsTest{
+		{"no tags", nil, "no tags"},
+		{"#deepwork", []string{"deepwork"}, ""},
+		{"Focus time #deepwork #External", []string{"deepwork", "external"}, "Focus time"},
+		{"<p>#deepwork</p><p>agenda</p>", []string{"deepwork"}, "agenda"},
+		{"#a #A repeated", []string{"a"}, "repeated"},
+		{"see https://example.com/page#section and issue #123", nil, "see https://example.com/page#section and issue #123"},
+		{"it&#39;s fine", nil, "it's fine"},
+	}
+
+	for i, test := range tagsTests {
+		if actual := extractTags(test.details); !slices.Equal(actual, test.expectedTags) {
+			t.Errorf("%d. Actual tags %q don't match expected %q", i, actual, test.expectedTags)
+		}
+		if actual := removeTags(cleanEventDetails(test.details)); actual != test.expectedDetails {
+			t.Errorf("%d. Actual details %q don't match expected %q", i, actual, test.expectedDetails)
+		}
+	}
+}
+
+func TestFilterByTag(t *testing.T) {
+	events := []event{{title: "focus", tags: []string{"deepwork"}}, {title: "sync"}, {title: "client", tags: []string{"external", "deepwork"}}}
+
+	if actual := filterByTag(events, ""); len(actual) != 3 {
+		t.Errorf("Actual %d events don't match expected 3 without filter", len(actual))
+	}
+	var actualTitles []string
+	for _, event := range filterByTag(events, "deepwork") {
+		actualTitles = append(actualTitles, event.title)
+	}
+	if !slices.Equal(actualTitles, []string{"focus", "client"}) {
+		t.Errorf("Actual %q don't match expected events with the tag", actualTitles)
+	}
+	if actual := filterByTag(events, "unknown"); len(actual) != 0 {
+		t.Errorf("Actual %d events don't match expected 0 for unknown tag", len(actual))
+	}
+}
+
+func TestFilterDeclined(t *testing.T) {
+	events := []event{{title: "standup", response: accepted}, {title: "optional", response: declined}, {title: "review", response: tentative}}
+
+	if actual := filterDeclined(events, false); len(actual) != 3 {
+		t.Errorf("Actual %d events don't match expected 3 when declined events are shown", len(actual))
+	}
+	var actualTitles []string
+	for _, event := range filterDeclined(events, true) {
+		actualTitles = append(actualTitles, event.title)
+	}
+	if !slices.Equal(actualTitles, []string{"standup", "review"}) {
+		t.Errorf("Actual %q don't match expected events without the declined one", actualTitles)
+	}
+}
+
+type cronSpecTest struct {
+	timeOfDay     string
+	weekday       int
+	expectedSpec  string
+	expectedError bool
+}
+
+func TestCreateCronSpec(t *testing.T) {
+	var cronSpecTests = []cronSpecTest{
+		{"09:00", 1, "0 9 * * 1", false},
+		{"17:45", 0, "45 17 * * 0", false},
+		{"08:30", -1, "30 8 * * *", false},
+		{"08:30", 7, "30 8 * * 0", false},
+		{"9am", 1, "", true},
+		{"25:00", 1, "", true},
+	}
+
+	for i, test := range cronSpecTests {
+		actual, err := createCronSpec(test.timeOfDay, test.weekday)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%d. Actual error %v doesn't match expected error = %t", i, err, test.expectedError)
+		}
+		if actual != test.expectedSpec {
+			t.Errorf("%d. Actual %q doesn't match expected %q", i, actual, test.expectedSpec)
+		}
+	}
+}
+
+func TestSummarizeEvents(t *testing.T) {
+	start := time.Date(2024, 11, 18, 9, 0, 0, 0, time.Local)
+	events := []event{
+		{start: start, end: start.Add(30 * time.Minute), response: accepted},
+		{start: start.Add(time.Hour), end: start.Add(2 * time.Hour)},
+		{start: start.Add(3 * time.Hour), end: start.Add(5 * time.Hour), response: declined},
+	}
+
+	count, total := summarizeEvents(events)
+	if count != 2 || total != 90*time.Minute {
+		t.Errorf("Actual %d meetings taking %v don't match expected 2 taking 1h30m", count, total)
+	}
+}
+
+type freeSlotTest struct {
+	from             time.Time
+	duration         time.Duration
+	expectedFreeSlot time.Time
+}
+
+func TestFindFreeSlot(t *testing.T) {
+	day := time.Date(2024, 11, 18, 0, 0, 0, 0, time.Local)
+	at := func(hour int, minute int) time.Time {
+		return day.Add(time.Duration(hour)*time.Hour + time.Duration(minute)*time.Minute)
+	}
+	events := []event{
+		{start: at(10, 0), end: at(10, 30)},
+		{start: at(9, 0), end: at(9, 45)},
+		{start: at(10, 45), end: at(11, 30)},
+		{start: at(11, 30), end: at(12, 0), response: declined},
+		{start: at(12, 30), end: at(13, 0)},
+	}
+	var freeSlotTests = []freeSlotTest{
+		{at(8, 0), 30 * time.Minute, at(8, 0)},
+		{at(8, 45), 30 * time.Minute, at(11, 30)},
+		{at(9, 15), 15 * time.Minute, at(9, 45)},
+		{at(9, 15), time.Hour, at(11, 30)},
+		{at(9, 15), 2 * time.Hour, at(13, 0)},
+		{at(10, 15), 30 * time.Minute, at(11, 30)},
+		{at(14, 0), 30 * time.Minute, at(14, 0)},
+	}
+
+	for i, test := range freeSlotTests {
+		if actual := findFreeSlot(events, test.from, test.duration); !actual.Equal(test.expectedFreeSlot) {
+			t.Errorf("%d. Actual %v doesn't match expected %v", i, actual.Format("15:04"), test.expectedFreeSlot.Format("15:04"))
+		}
+	}
+}
+
+type hexColorTest struct {
+	hex           string
+	expected      color.NRGBA
+	expectedError bool
+}
+
+func TestParseHexColor(t *testing.T) {
+	var hexColorTests = []hexColorTest{
+		{"#ff8000", color.NRGBA{R: 0xff, G: 0x80, A: 0xff}, false},
+		{"#00A0ff", color.NRGBA{G: 0xa0, B: 0xff, A: 0xff}, false},
+		{"ff8000", color.NRGBA{}, true},
+		{"#ff80", color.NRGBA{}, true},
+		{"#ff800000", color.NRGBA{}, true},
+		{"#gg8000", color.NRGBA{}, true},
+	}
+
+	for i, test := range hexColorTests {
+		actual, err := parseHexColor(test.hex)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%d. Actual error %v doesn't match expected error = %t", i, err, test.expectedError)
+		}
+		if actual != test.expected {
+			t.Errorf("%d. Actual %v doesn't match expected %v", i, actual, test.expected)
+		}
+		if !test.expectedError && formatHexColor(actual) != strings.ToLower(test.hex) {
+			t.Errorf("%d. Actual formatted %q doesn't match the parsed %q", i, formatHexColor(actual), test.hex)
+		}
+	}
+}
+
+func TestAccentTheme(t *testing.T) {
+	accent := &accentTheme{Theme: test.Theme(), accent: color.NRGBA{R: 0xff, G: 0xeb, B: 0x3b, A: 0xff}}
+	if actual := accent.Color(theme.ColorNamePrimary, theme.VariantLight); actual != accent.accent {
+		t.Errorf("Actual primary %v doesn't match the accent %v", actual, accent.accent)
+	}
+	if actual := accent.Color(theme.ColorNameForegroundOnPrimary, theme.VariantDark); actual != color.Black {
+		t.Errorf("Actual foreground on a light accent %v is not black", actual)
+	}
+	expectedBackground := test.Theme().Color(theme.ColorNameBackground, theme.VariantDark)
+	if actual := accent.Color(theme.ColorNameBackground, theme.VariantDark); actual != expectedBackground {
+		t.Errorf("Actual background %v doesn't match the base theme's %v", actual, expectedBackground)
+	}
+
+	accent.accent = color.NRGBA{R: 0x3f, G: 0x51, B: 0xb5, A: 0xff}
+	if actual := accent.Color(theme.ColorNameForegroundOnPrimary, theme.VariantLight); actual != color.White {
+		t.Errorf("Actual foreground on a dark accent %v is not white", actual)
+	}
+}
+
+type statusTest struct {
+	events       []event
+	expectedBusy bool
+	expectedNext string
+	expectedText string
+}
+
+func TestCreateStatus(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	current := now().Truncate(time.Minute)
+	ongoing := event{title: "standup", start: current.Add(-10 * time.Minute), end: current.Add(20 * time.Minute)}
+	later := event{title: "review", start: current.Add(time.Hour), end: current.Add(2 * time.Hour)}
+	soon := event{title: "1:1", start: current.Add(30 * time.Minute), end: current.Add(time.Hour)}
+	past := event{title: "breakfast", start: current.Add(-2 * time.Hour), end: current.Add(-time.Hour)}
+	declinedOngoing := ongoing
+	declinedOngoing.response = declined
+	freeOngoing := ongoing
+	freeOngoing.free = true
+	blockedOngoing := ongoing
+	blockedOngoing.title = "Lunch"
+	dailyApp.Preferences().SetStringList("title-blocklist", []string{"^Lunch$"})
+	var statusTests = []statusTest{
+		{nil, false, "", "Free"},
+		{[]event{past}, false, "", "Free"},
+		{[]event{later, soon}, false, "1:1", "Next: 1:1 (" + soon.start.Format("3:04PM") + ")"},
+		{[]event{ongoing, later}, true, "review", "Busy: standup (until " + ongoing.end.Format("3:04PM") + ")"},
+		{[]event{declinedOngoing, later}, false, "review", "Next: review (" + later.start.Format("3:04PM") + ")"},
+		{[]event{freeOngoing, later}, false, "review", "Next: review (" + later.start.Format("3:04PM") + ")"},
+		{dimBlockedEvents([]event{blockedOngoing, later}), false, "review", "Next: review (" + later.start.Format("3:04PM") + ")"},
+	}
+
+	for i, test := range statusTests {
+		actual := createStatus(test.events)
+		if actual.Busy != test.expectedBusy || actual.Next != test.expectedNext || actual.Text != test.expectedText {
+			t.Errorf("%d. Actual %+v doesn't match expected busy = %t, next = %q, text = %q", i, actual, test.expectedBusy, test.expectedNext, test.expectedText)
+		}
+	}
+}
+
+func TestChangeDayNavigationLimit(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetInt("navigation-limit-days", 2)
+	previousDay, nextDay = widget.NewButton("", nil), widget.NewButton("", nil)
+	defer func() { previousDay, nextDay, displayDay = nil, nil, time.Time{} }()
+
+	today := now()
+	changeDay(today.AddDate(0, 0, 2))
+	if !isOnSameDay(displayDay, today.AddDate(0, 0, 2)) || !nextDay.Disabled() || previousDay.Disabled() {
+		t.Errorf("Actual day %v or buttons (previous disabled = %t, next disabled = %t) don't match the limit", displayDay, previousDay.Disabled(), nextDay.Disabled())
+	}
+
+	changeDay(today.AddDate(0, 0, 3))
+	if !isOnSameDay(displayDay, today.AddDate(0, 0, 2)) {
+		t.Errorf("Actual day %v changed beyond the limit", displayDay)
+	}
+
+	changeDay(today)
+	if previousDay.Disabled() || nextDay.Disabled() {
+		t.Errorf("Navigation buttons are disabled within the limit")
+	}
+
+	dailyApp.Preferences().SetInt("navigation-limit-days", 0)
+	if !isWithinNavigationLimit(today.AddDate(1, 0, 0)) {
+		t.Errorf("Navigation is limited when the limit is disabled")
+	}
+}
+
+type rateLimitDelayTest struct {
+	err             error
+	expectedDelay   time.Duration
+	expectedLimited bool
+}
+
+func TestRateLimitDelay(t *testing.T) {
+	retryAfter := func(value string) http.Header {
+		return http.Header{"Retry-After": []string{value}}
+	}
+	inTwoMinutes := time.Now().Add(2 * time.Minute).UTC().Format(http.TimeFormat)
+	var rateLimitDelayTests = []rateLimitDelayTest{
+		{&googleapi.Error{Code: http.StatusTooManyRequests, Header: retryAfter("30")}, 30 * time.Second, true},
+		{&googleapi.Error{Code: http.StatusTooManyRequests}, defaultRateLimitDelay, true},
+		{&googleapi.Error{Code: http.StatusTooManyRequests, Header: retryAfter("soon")}, defaultRateLimitDelay, true},
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "userRateLimitExceeded"}}, Header: retryAfter("5")}, 5 * time.Second, true},
+		{&googleapi.Error{Code: http.StatusForbidden}, 0, false},
+		{errors.New("something failed"), 0, false},
+		{nil, 0, false},
+	}
+
+	for i, test := range rateLimitDelayTests {
+		actual, limited := rateLimitDelay(test.err)
+		if actual != test.expectedDelay || limited != test.expectedLimited {
+			t.Errorf("%d. Actual %v, limited = %t don't match expected %v, %t", i, actual, limited, test.expectedDelay, test.expectedLimited)
+		}
+	}
+
+	actual, limited := rateLimitDelay(&googleapi.Error{Code: http.StatusTooManyRequests, Header: retryAfter(inTwoMinutes)})
+	if !limited || actual <= time.Minute || actual > 2*time.Minute {
+		t.Errorf("Actual delay %v doesn't match the Retry-After date", actual)
+	}
+}
+
+type meetingLinkInDetailsTest struct {
+	location         string
+	details          string
+	expectedLocation string
+}
+
+func TestUseMeetingLinkInDetails(t *testing.T) {
+	var meetingLinkInDetailsTests = []meetingLinkInDetailsTest{
+		{"Room 1", `<p>Join Zoom Meeting <a href="https://us02web.zoom.us/j/8123?pwd=a&amp;b=1">here</a></p>`, "https://us02web.zoom.us/j/8123?pwd=a&b=1"},
+		{"Webex", "Join: https://company.webex.com/meet/jdoe", "https://company.webex.com/meet/jdoe"},
+		{"", "Meet at https://meet.google.com/abc-defg-hij.", "https://meet.google.com/abc-defg-hij"},
+		{"https://zoom.us/j/1234", "Backup: https://meet.google.com/abc-defg-hij", "https://zoom.us/j/1234"},
+		{"tel:+1-555-0100", "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc", "tel:+1-555-0100"},
+		{"Room 1", "Agenda at https://docs.example.com/agenda", "Room 1"},
+	}
+
+	for i, test := range meetingLinkInDetailsTests {
+		actual := event{location: test.location, details: test.details}
+		actual.useMeetingLinkInDetails()
+		if actual.location != test.expectedLocation {
+			t.Errorf("%d. Actual %q doesn't match expected %q", i, actual.location, test.expectedLocation)
+		}
+	}
+}
+
+func TestRenderEventsCopyMeetingLink(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	mainWindow = dailyApp.NewWindow("test")
+	defer func() { mainWindow = nil }()
+	eventsList = container.NewVBox()
+	renderedEvents = map[string]*ui.Event{}
+
+	start := now().Add(time.Hour)
+	meeting := event{id: "event1", title: "meeting", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234"}
+	finished := event{id: "event2", title: "finished", start: start.Add(-3 * time.Hour), end: start.Add(-2 * time.Hour), location: "https://zoom.us/j/2345"}
+	room := event{id: "event3", title: "in person", start: start, end: start.Add(time.Hour), location: "Room 1"}
+	renderEvents([]event{meeting, finished, room})
+
+	buttons := renderedEvents[meeting.renderKey()].TitleButtons
+	if len(buttons) != 2 || buttons[1].Icon != theme.ContentCopyIcon() {
+		t.Fatalf("Actual %d buttons don't include the copy button", len(buttons))
+	}
+	test.Tap(buttons[1])
+	if actual := mainWindow.Clipboard().Content(); actual != meeting.location {
+		t.Errorf("Actual clipboard %q doesn't match the meeting link", actual)
+	}
+	if buttons[1].Icon != theme.ConfirmIcon() {
+		t.Errorf("Copying was not confirmed")
+	}
+
+	if actual := len(renderedEvents[finished.renderKey()].TitleButtons); actual != 1 {
+		t.Errorf("Actual %d buttons of a finished meeting don't match expected 1", actual)
+	}
+	if actual := len(renderedEvents[room.renderKey()].TitleButtons); actual != 0 {
+		t.Errorf("Actual %d buttons of an in-person event don't match expected 0", actual)
+	}
+}
+
+func TestRenderEventsLocationLink(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	eventsList = container.NewVBox()
+	renderedEvents = map[string]*ui.Event{}
+
+	start := now().Add(time.Hour)
+	inPerson := event{id: "event1", title: "lunch", start: start, end: start.Add(time.Hour), location: "1 Main St, Springfield & Co"}
+	virtual := event{id: "event2", title: "meeting", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234"}
+	renderEvents([]event{inPerson, virtual})
+
+	detail := renderedEvents[inPerson.renderKey()].Detail.(*fyne.Container)
+	link, isLink := detail.Objects[0].(*widget.Hyperlink)
+	if !isLink {
+		t.Fatalf("Actual first detail %T is not the location link", detail.Objects[0])
+	}
+	expectedUrl := "https://maps.google.com/?q=1+Main+St%2C+Springfield+%26+Co"
+	if link.Text != inPerson.location || link.URL.String() != expectedUrl {
+		t.Errorf("Actual link %q to %q doesn't match expected %q", link.Text, link.URL, expectedUrl)
+	}
+
+	for _, object := range renderedEvents[virtual.renderKey()].Detail.(*fyne.Container).Objects {
+		if _, isLink := object.(*widget.Hyperlink); isLink {
+			t.Errorf("Virtual meeting has a location link")
+		}
+	}
+}
+
+func TestShowNotifiedEvent(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	mainWindow = dailyApp.NewWindow("test")
+	defer func() { mainWindow = nil }()
+	eventsList = container.NewVBox()
+	renderedEvents = map[string]*ui.Event{}
+	displayDay = now()
+	defer func() { displayDay = time.Time{} }()
+
+	start := now().Add(5 * time.Minute)
+	meeting := event{id: "standup", title: "standup", start: start, end: start.Add(15 * time.Minute)}
+	renderEvents([]event{meeting})
+	var opened bool
+	renderedEvents[meeting.renderKey()].OnOpened = func() { opened = true }
+	mainWindowHidden = true
+
+	showNotifiedEvent(meeting)
+	if mainWindowHidden {
+		t.Errorf("Main window was not shown")
+	}
+	if !opened {
+		t.Errorf("Details of the notified event were not opened")
+	}
+}
+
+func TestToggleExpandAll(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	mainWindow = dailyApp.NewWindow("test")
+	defer func() { mainWindow = nil }()
+	eventsList = container.NewVBox()
+	renderedEvents = map[string]*ui.Event{}
+	defer func() { allExpanded = false }()
+
+	start := now().Add(time.Hour)
+	standup := event{id: "standup", title: "standup", start: start, end: start.Add(15 * time.Minute)}
+	review := event{id: "review", title: "review", start: start.Add(time.Hour), end: start.Add(2 * time.Hour)}
+	renderEvents([]event{standup})
+	expandButton := widget.NewButtonWithIcon("", theme.MenuDropDownIcon(), nil)
+
+	toggleExpandAll(expandButton)
+	if !renderedEvents[standup.renderKey()].IsOpen() || expandButton.Icon != theme.MenuDropUpIcon() {
+		t.Errorf("Events were not expanded")
+	}
+	renderEvents([]event{standup, review})
+	if !renderedEvents[review.renderKey()].IsOpen() {
+		t.Errorf("Event rendered after expanding all was not expanded")
+	}
+
+	toggleExpandAll(expandButton)
+	if renderedEvents[standup.renderKey()].IsOpen() || renderedEvents[review.renderKey()].IsOpen() || expandButton.Icon != theme.MenuDropDownIcon() {
+		t.Errorf("Events were not collapsed")
+	}
+}
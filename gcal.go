@@ -4,19 +4,27 @@ import (
 	"context"
 	"crypto/rand"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"html"
 	"log/slog"
 	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"syscall"
 	"time"
 
+	"fyne.io/fyne/v2"
 	"golang.org/x/oauth2"
 	"golang.org/x/oauth2/google"
 	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
 	"google.golang.org/api/option"
 )
 
@@ -25,21 +33,58 @@ const (
 	clientSecretFile = "secrets/client.json"
 )
 
+// A video conferencing service whose links can be found in the description of events created outside of Google
+type meetingProvider struct {
+	name      string
+	linkRegex *regexp.Regexp
+}
+
+var meetingProviders = []meetingProvider{
+	{"Zoom", regexp.MustCompile(`https://(?:[\w-]+\.)?zoom\.us/(?:j|my|w)/[^\s"'<>]+`)},
+	{"Google Meet", regexp.MustCompile(`https://meet\.google\.com/[a-z]+-[a-z]+-[a-z]+`)},
+	// government clouds are in subdomains of teams.microsoft.us
+	{"Microsoft Teams", regexp.MustCompile(`https://(?:[\w-]+\.)?teams\.(?:microsoft\.com|microsoft\.us|live\.com)/(?:l/meetup-join|meet)/[^\s"'<>]+`)},
+	{"Webex", regexp.MustCompile(`https://[\w-]+\.webex\.com/(?:meet|join|[\w-]+/j\.php)[^\s"'<>]*`)},
+}
+
+const (
+	// Days retrieved before and after the requested day, unless set in the calendar-buffer-days preference
+	defaultBufferDays = 5
+	// Days from the edge of the buffer at which it is extended, unless set in the calendar-buffer-threshold preference
+	defaultBufferThreshold = 2
+)
+
+// The fields of the events that are converted
+const eventFields = "attachments, attendees, conferenceData, created, updated, description, start, end, etag, eventType, hangoutLink, htmlLink, id, location, organizer, originalStartTime, recurringEventId, reminders, status, summary, transparency"
+
 type googleCalendar struct {
 	service          *calendar.Service
 	eventsBuffer     []event
+	calendarBuffers  map[string][]event
 	requestStartDate time.Time
 	requestEndDate   time.Time
 }
 
-func startGCalOAuthFlow() (string, error) {
-	slog.Info("Starting OAuth flow for Google Calendar")
+// Gets a token for Google Calendar. In manual mode, the browser is not opened automatically and the user can paste the
+// authorization code, which allows authenticating from a remote machine that can't reach the local redirect
+func startGCalOAuthFlow(parent fyne.Window, manual bool) (string, error) {
+	slog.Info("Starting OAuth flow for Google Calendar. Manual = " + strconv.FormatBool(manual))
 
 	config, err := createOAuthConfig()
 	if err != nil {
 		slog.Error("Failed to create config", "error", err)
 		return "", err
 	}
+
+	return startOAuthFlow(config, parent, manual, []oauth2.AuthCodeOption{oauth2.AccessTypeOffline}, nil)
+}
+
+// Returned by the OAuth flow when the user closed the dialog to paste the code. There is nothing to report to them
+var errOAuthCancelled = errors.New("OAuth flow cancelled")
+
+// Gets a token with the authorization code flow, receiving the code in a local redirect or, in manual mode, from the
+// user. The options are added to the authorization URL and to the exchange of the code respectively
+func startOAuthFlow(config *oauth2.Config, parent fyne.Window, manual bool, authOptions []oauth2.AuthCodeOption, exchangeOptions []oauth2.AuthCodeOption) (string, error) {
 	listener, err := net.Listen("tcp", "localhost:0")
 	if err != nil {
 		slog.Error("Failed to create listener", "error", err)
@@ -49,7 +94,7 @@ func startGCalOAuthFlow() (string, error) {
 
 	config.RedirectURL = fmt.Sprintf("http://localhost:%d/callback", port)
 	state := generateRandomState()
-	authURL := config.AuthCodeURL(state, oauth2.AccessTypeOffline)
+	authURL := config.AuthCodeURL(state, authOptions...)
 
 	parsedURL, err := url.Parse(authURL)
 	if err != nil {
@@ -57,57 +102,119 @@ func startGCalOAuthFlow() (string, error) {
 		return "", err
 	}
 
-	// Open the URL in the user's browser
-	err = dailyApp.OpenURL(parsedURL)
-	if err != nil {
-		slog.Error("Failed to open OAuth URL", "error", err)
-		return "", err
+	// the token is sent once, by whichever of the redirect or the pasted code is exchanged first
+	tokens := make(chan string, 1)
+	serveErrors := make(chan error, 1)
+	cancelled := make(chan struct{})
+	var cancelOnce sync.Once
+	cancel := func() {
+		cancelOnce.Do(func() { close(cancelled) })
 	}
 
-	done := make(chan bool)
-
-	server := &http.Server{Addr: fmt.Sprintf(":%d", port)}
-	var tokenResult string
-	http.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
-		if r.URL.Query().Get("state") != state {
-			http.Error(w, "Invalid state", http.StatusBadRequest)
-			return
-		}
-
-		code := r.URL.Query().Get("code")
-		token, err := config.Exchange(context.Background(), code)
+	mux := http.NewServeMux()
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: mux}
+	defer server.Shutdown(context.Background())
+	exchangeCode := func(code string) error {
+		token, err := config.Exchange(proxiedContext(), code, exchangeOptions...)
 		if err != nil {
-			http.Error(w, "Failed to exchange token", http.StatusInternalServerError)
-			return
+			slog.Error("Failed to exchange token", "error", err)
+			return err
 		}
 
 		slog.Info("Authentication successful!")
 
 		tokenJSON, err := json.Marshal(token)
 		if err != nil {
-			http.Error(w, "Failed to marshal token", http.StatusInternalServerError)
-			return
+			slog.Error("Failed to marshal token", "error", err)
+			return err
+		}
+		select {
+		case tokens <- string(tokenJSON):
+		default:
 		}
+		return nil
+	}
 
-		w.Header().Set("Content-Type", "text/html")
-		w.Write([]byte("<html><body><h1>Authentication Complete</h1></body></html>"))
+	mux.HandleFunc("/callback", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("state") != state {
+			http.Error(w, message("auth-invalid-state"), http.StatusBadRequest)
+			return
+		}
 
-		done <- true
-		go server.Shutdown(context.Background())
+		err := exchangeCode(r.URL.Query().Get("code"))
+		if err != nil {
+			http.Error(w, message("auth-failed"), http.StatusInternalServerError)
+			return
+		}
 
-		tokenResult = string(tokenJSON)
+		w.Header().Set("Content-Type", "text/html")
+		w.Write([]byte("<html><body><h1>" + html.EscapeString(message("auth-complete")) + "</h1></body></html>"))
 	})
 
 	go func() {
 		if err := server.Serve(listener); err != http.ErrServerClosed {
 			slog.Error("Server error", "error", err)
+			serveErrors <- err
 		}
-		done <- true
 	}()
 
-	<-done // Wait for the callback to complete
+	onCodePasted := func(pasted string) error {
+		code, err := parseAuthorizationCode(pasted, state)
+		if err != nil {
+			return err
+		}
+		return exchangeCode(code)
+	}
+
+	showManually := func() {
+		runOnUi(func() { showManualUrl(authURL, parent, onCodePasted, cancel) })
+	}
+	if manual {
+		showManually()
+	} else {
+		// Open the URL in the user's browser
+		err = dailyApp.OpenURL(parsedURL)
+		if err != nil {
+			slog.Warn("Failed to open OAuth URL. Asking the user to open it manually", "error", err)
+			showManually()
+		}
+	}
+
+	// Wait for the callback to complete
+	select {
+	case token := <-tokens:
+		return token, nil
+	case err := <-serveErrors:
+		return "", err
+	case <-cancelled:
+		// the dialog is also closed after the pasted code was exchanged
+		select {
+		case token := <-tokens:
+			return token, nil
+		default:
+			slog.Info("OAuth flow was cancelled by closing the dialog")
+			return "", errOAuthCancelled
+		}
+	}
+}
+
+// Gets the authorization code from what the user pasted, which can be either the code itself or the whole url the
+// browser was redirected to
+func parseAuthorizationCode(pasted string, state string) (string, error) {
+	pasted = strings.TrimSpace(pasted)
+	if pasted == "" {
+		return "", errors.New(message("no-auth-code"))
+	}
 
-	return tokenResult, nil
+	redirectUrl, err := url.Parse(pasted)
+	if err == nil && redirectUrl.Query().Has("code") {
+		if redirectUrl.Query().Get("state") != state {
+			return "", errors.New(message("invalid-auth-state"))
+		}
+		return redirectUrl.Query().Get("code"), nil
+	}
+
+	return pasted, nil
 }
 
 func generateRandomState() string {
@@ -123,28 +230,229 @@ func generateRandomState() string {
 func newGoogleCalendarEventSource() (*googleCalendar, error) {
 	result := googleCalendar{}
 
+	var err error
+	result.service, err = newCalendarService(dailyApp.Preferences().String("calendar-token"))
+	if err != nil {
+		return nil, err
+	}
+	result.retrieveDefaultReminder()
+	result.loadCachedEvents()
+
+	return &result, nil
+}
+
+// Stores the minutes before the start of the calendar's default popup reminder. The earliest one is used if there are
+// several
+func (gcal *googleCalendar) retrieveDefaultReminder() {
+	// with several calendars, the first one is the main one
+	calendarId := calendarIds()[0]
+	entry, err := gcal.service.CalendarList.Get(calendarId).Fields("defaultReminders").Do()
+	if isInsufficientScopeError(err) {
+		slog.Warn("Could not retrieve the default reminders of the calendar. Asking the user to reconnect", "error", err)
+		promptReconnect()
+		return
+	}
+	if err != nil {
+		slog.Warn("Could not retrieve the default reminders of the calendar", "error", err)
+		return
+	}
+
+	reminders := extractReminders(&calendar.EventReminders{Overrides: entry.DefaultReminders})
+	if len(reminders) == 0 {
+		slog.Debug("Calendar has no default popup reminders")
+		dailyApp.Preferences().RemoveValue("calendar-default-reminder")
+		return
+	}
+
+	slog.Debug("Calendar default reminder is " + strconv.Itoa(slices.Max(reminders)) + " minute(s) before events")
+	dailyApp.Preferences().SetInt("calendar-default-reminder", slices.Max(reminders))
+}
+
+// Gets the options to connect to Google Calendar with the token. Tests replace it to connect to a fake server without
+// needing the client secret
+var calendarServiceOptions = func(token string) ([]option.ClientOption, error) {
 	config, err := createOAuthConfig()
 	if err != nil {
 		return nil, err
 	}
 
 	tok := &oauth2.Token{}
-	tokenReader := strings.NewReader(dailyApp.Preferences().String("calendar-token"))
+	tokenReader := strings.NewReader(token)
 	err = json.NewDecoder(tokenReader).Decode(tok)
 	if err != nil {
 		slog.Error("Error decoding token")
 		return nil, err
 	}
 
-	client := config.Client(context.Background(), tok)
+	ctx := proxiedContext()
+	tokenSource := &persistingTokenSource{source: config.TokenSource(ctx, tok), last: tok, preference: "calendar-token"}
+	client := oauth2.NewClient(ctx, tokenSource)
+
+	return []option.ClientOption{option.WithHTTPClient(client)}, nil
+}
+
+// A token source that stores the tokens it gets in a preference when they change, so that the ones refreshed by the
+// oauth2 library survive restarts
+type persistingTokenSource struct {
+	source     oauth2.TokenSource
+	last       *oauth2.Token
+	preference string
+	mutex      sync.Mutex
+}
+
+func (persisting *persistingTokenSource) Token() (*oauth2.Token, error) {
+	token, err := persisting.source.Token()
+	if err != nil {
+		return nil, err
+	}
+
+	persisting.mutex.Lock()
+	defer persisting.mutex.Unlock()
+	if persisting.last != nil && token.AccessToken == persisting.last.AccessToken && token.Expiry.Equal(persisting.last.Expiry) {
+		return token, nil
+	}
+
+	slog.Info("Calendar token was refreshed. Storing it")
+	serialized, err := json.Marshal(token)
+	if err != nil {
+		slog.Error("Could not encode refreshed token", "error", err)
+		return token, nil
+	}
+	dailyApp.Preferences().SetString(persisting.preference, string(serialized))
+	persisting.last = token
+
+	return token, nil
+}
+
+func newCalendarService(token string) (*calendar.Service, error) {
+	options, err := calendarServiceOptions(token)
+	if err != nil {
+		return nil, err
+	}
 
 	ctx := context.Background()
-	result.service, err = calendar.NewService(ctx, option.WithHTTPClient(client))
+	service, err := calendar.NewService(ctx, options...)
 	if err != nil {
 		slog.Error("Unable to retrieve Calendar client", "error", err)
+		return nil, err
 	}
 
-	return &result, nil
+	return service, nil
+}
+
+// Checks that the calendars in the calendar-id, separated by commas, are among the ones the user has access to
+func validateCalendarId(token string, calendarId string) error {
+	var unknownIds []string
+	for _, id := range strings.Split(calendarId, ",") {
+		if id = strings.TrimSpace(id); id != "" && id != "primary" {
+			unknownIds = append(unknownIds, id)
+		}
+	}
+	if len(unknownIds) == 0 {
+		return nil
+	}
+
+	service, err := newCalendarService(token)
+	if err != nil {
+		return err
+	}
+
+	slog.Debug("Validating calendarId = " + calendarId)
+	entries, err := listCalendars(service)
+	if isInsufficientScopeError(err) {
+		return errors.New(message("reconnect-scopes"))
+	}
+	if err != nil {
+		return err
+	}
+	var validIds []string
+	for _, entry := range entries {
+		unknownIds = slices.DeleteFunc(unknownIds, func(id string) bool { return id == entry.Id })
+		validIds = append(validIds, entry.Id+" ("+entry.Summary+")")
+	}
+
+	if len(unknownIds) > 0 {
+		return errors.New(message("calendar-not-found") + strings.Join(unknownIds, ", ") + "\n" + message("valid-calendar-ids") + "\nprimary\n" + strings.Join(validIds, "\n"))
+	}
+
+	return nil
+}
+
+// Gets all the calendars the user has access to
+func listCalendars(service *calendar.Service) ([]*calendar.CalendarListEntry, error) {
+	var result []*calendar.CalendarListEntry
+	err := service.CalendarList.List().Fields("nextPageToken", "items(id, summary, primary)").Pages(context.Background(), func(list *calendar.CalendarList) error {
+		result = append(result, list.Items...)
+		return nil
+	})
+	if err != nil {
+		slog.Error("Unable to retrieve calendar list", "error", err)
+		return nil, err
+	}
+
+	return result, nil
+}
+
+// Gets readable names for the calendars and the ID of each name. Names are the summaries, with the ID added when
+// several calendars have the same one. The primary calendar uses the "primary" ID, like the default of the calendar-id
+// preference
+func createCalendarOptions(entries []*calendar.CalendarListEntry) ([]string, map[string]string) {
+	summaries := make(map[string]int)
+	for _, entry := range entries {
+		summaries[entry.Summary]++
+	}
+
+	var names []string
+	ids := make(map[string]string)
+	for _, entry := range entries {
+		name := entry.Summary
+		if name == "" || summaries[name] > 1 {
+			name = strings.TrimSpace(name + " (" + entry.Id + ")")
+		}
+		id := entry.Id
+		if entry.Primary {
+			id = "primary"
+		}
+		names = append(names, name)
+		ids[name] = id
+	}
+
+	return names, ids
+}
+
+// Gets the names of the calendar options whose IDs are in the calendar IDs, separated by commas
+func findPickedCalendars(calendarIds string, names []string, ids map[string]string) []string {
+	current := strings.Split(calendarIds, ",")
+	for i := range current {
+		current[i] = strings.TrimSpace(current[i])
+	}
+	var result []string
+	for _, name := range names {
+		if slices.Contains(current, ids[name]) {
+			result = append(result, name)
+		}
+	}
+
+	return result
+}
+
+// Gets the calendar IDs, separated by commas, with the picked calendar options instead of the ones in calendarIds. IDs
+// that are not among the options, like ones typed by the user, are kept
+func pickCalendars(calendarIds string, names []string, ids map[string]string, picked []string) string {
+	var result []string
+	for _, id := range strings.Split(calendarIds, ",") {
+		isOption := slices.ContainsFunc(names, func(name string) bool { return ids[name] == strings.TrimSpace(id) })
+		if id = strings.TrimSpace(id); id != "" && !isOption {
+			result = append(result, id)
+		}
+	}
+	for _, name := range names {
+		if slices.Contains(picked, name) {
+			result = append(result, ids[name])
+		}
+	}
+
+	return strings.Join(result, ", ")
 }
 
 func createOAuthConfig() (*oauth2.Config, error) {
@@ -154,7 +462,7 @@ func createOAuthConfig() (*oauth2.Config, error) {
 		return nil, err
 	}
 
-	config, err := google.ConfigFromJSON(clientSecret, calendar.CalendarEventsReadonlyScope)
+	config, err := google.ConfigFromJSON(clientSecret, calendar.CalendarEventsScope, calendar.CalendarReadonlyScope)
 	if err != nil {
 		slog.Error("Unable to parse client secret file to config: %v", "error", err)
 		return nil, err
@@ -175,18 +483,22 @@ func (gcal *googleCalendar) getEvents(day time.Time, fullRefresh bool) ([]event,
 		refreshed = true
 	}
 
-	const minBufferThreshold = 2
+	halfWindow, threshold := bufferWindow()
+	prefetchDays := dailyApp.Preferences().IntWithFallback("calendar-prefetch-days", 5)
 
-	if int(day.Sub(gcal.requestStartDate).Hours()/24) < minBufferThreshold {
+	// when getting close to an edge, the buffer is extended in that direction since the user is likely to keep going
+	if int(day.Sub(gcal.requestStartDate).Hours()/24) < threshold {
 		slog.Debug("Too close to buffer start")
-		err := gcal.retrieveEventsAround(gcal.requestStartDate)
+		start := minTime(gcal.requestStartDate, day).AddDate(0, 0, -prefetchDays)
+		err := gcal.retrieveEventsBetween(start, day.AddDate(0, 0, halfWindow))
 		if err != nil {
 			return nil, false, err
 		}
 		refreshed = true
-	} else if int(gcal.requestEndDate.Sub(day).Hours()/24) < minBufferThreshold {
+	} else if int(gcal.requestEndDate.Sub(day).Hours()/24) < threshold {
 		slog.Debug("Too close to buffer end")
-		err := gcal.retrieveEventsAround(gcal.requestEndDate)
+		end := maxTime(gcal.requestEndDate, day).AddDate(0, 0, prefetchDays)
+		err := gcal.retrieveEventsBetween(day.AddDate(0, 0, -halfWindow), end)
 		if err != nil {
 			return nil, false, err
 		}
@@ -195,7 +507,7 @@ func (gcal *googleCalendar) getEvents(day time.Time, fullRefresh bool) ([]event,
 
 	if fullRefresh && !refreshed {
 		slog.Debug("Forcing retrieve of events")
-		err := gcal.retrieveEventsAround(day)
+		err := gcal.retrieveEventsBetween(gcal.requestStartDate, gcal.requestEndDate)
 		if err != nil {
 			return nil, false, err
 		}
@@ -212,39 +524,289 @@ func (gcal *googleCalendar) getEvents(day time.Time, fullRefresh bool) ([]event,
 	return result, refreshed, nil
 }
 
+func (gcal *googleCalendar) getBufferedEvents() []event {
+	return gcal.eventsBuffer
+}
+
+func (gcal *googleCalendar) getEventsBetween(start time.Time, end time.Time) ([]event, error) {
+	if len(gcal.eventsBuffer) == 0 || start.Before(gcal.requestStartDate) || end.After(gcal.requestEndDate) {
+		slog.Debug("Extending buffer to retrieve events between " + start.Format(time.RFC3339) + " and " + end.Format(time.RFC3339))
+		bufferStart, bufferEnd := start, end
+		if len(gcal.eventsBuffer) > 0 {
+			bufferStart, bufferEnd = minTime(gcal.requestStartDate, start), maxTime(gcal.requestEndDate, end)
+		}
+		// the end is rounded down to the start of its day
+		err := gcal.retrieveEventsBetween(bufferStart, bufferEnd.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result []event
+	for _, event := range gcal.eventsBuffer {
+		if !event.start.Before(start) && event.start.Before(end) {
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
+
 func (gcal *googleCalendar) retrieveEventsAround(day time.Time) error {
-	_, timezoneOffset := day.Zone()
-	const requestHalfWindow int = 5
-	gcal.requestStartDate = day.AddDate(0, 0, -requestHalfWindow).Truncate(24 * time.Hour).Add(time.Second * time.Duration(-timezoneOffset))
-	gcal.requestEndDate = day.AddDate(0, 0, requestHalfWindow).Truncate(24 * time.Hour).Add(time.Second * time.Duration(-timezoneOffset))
-	calendarId := dailyApp.Preferences().String("calendar-id")
-	slog.Info("Retrieving events between " + gcal.requestStartDate.Format(time.RFC3339) + " and " + gcal.requestEndDate.Format(time.RFC3339) + " for calendarId = " + calendarId)
-	response, err := gcal.service.Events.List(calendarId).
+	halfWindow, _ := bufferWindow()
+	return gcal.retrieveEventsBetween(day.AddDate(0, 0, -halfWindow), day.AddDate(0, 0, halfWindow))
+}
+
+// The calendar-buffer-days and calendar-buffer-threshold preferences that were last validated, and the buffer window
+// they result in. They are only validated again when they change, so invalid ones are not reported on every refresh
+var (
+	bufferSettings      [2]int
+	validBufferWindow   [2]int
+	bufferSettingsMutex sync.Mutex
+)
+
+// Gets the days retrieved around the requested day and how close to the edge of the buffer it has to be to extend it.
+// The defaults are used if the threshold is not less than the half-window, since every day would be too close to an
+// edge
+func bufferWindow() (int, int) {
+	halfWindow := dailyApp.Preferences().IntWithFallback("calendar-buffer-days", defaultBufferDays)
+	threshold := dailyApp.Preferences().IntWithFallback("calendar-buffer-threshold", defaultBufferThreshold)
+	bufferSettingsMutex.Lock()
+	defer bufferSettingsMutex.Unlock()
+	if validBufferWindow != [2]int{} && bufferSettings == [2]int{halfWindow, threshold} {
+		return validBufferWindow[0], validBufferWindow[1]
+	}
+
+	bufferSettings, validBufferWindow = [2]int{halfWindow, threshold}, [2]int{halfWindow, threshold}
+	if halfWindow < 1 || threshold < 0 || threshold >= halfWindow {
+		slog.Warn(fmt.Sprintf("Invalid calendar-buffer-days = %d and calendar-buffer-threshold = %d. Using the defaults", halfWindow, threshold))
+		validBufferWindow = [2]int{defaultBufferDays, defaultBufferThreshold}
+	}
+
+	return validBufferWindow[0], validBufferWindow[1]
+}
+
+// Replaces the buffer with the events between the start of the days of start and end. The buffer and its range are
+// left as they were if any calendar fails, so that the next attempt is decided from what is actually buffered
+func (gcal *googleCalendar) retrieveEventsBetween(start time.Time, end time.Time) error {
+	start, end = startOfDay(start), startOfDay(end)
+	calendarBuffers := make(map[string][]event)
+	for _, calendarId := range calendarIds() {
+		events, err := gcal.retrieveCalendarEvents(calendarId, start, end)
+		if err != nil {
+			return err
+		}
+		calendarBuffers[calendarId] = events
+	}
+	gcal.requestStartDate = start
+	gcal.requestEndDate = end
+	gcal.calendarBuffers = calendarBuffers
+	gcal.eventsBuffer = mergeCalendarEvents(calendarIds(), calendarBuffers)
+	saveEventsCache(gcal.requestStartDate, gcal.requestEndDate, gcal.eventsBuffer)
+
+	return nil
+}
+
+// Fills the buffer with the events saved by the last retrieval so that they can be shown before retrieving them again
+func (gcal *googleCalendar) loadCachedEvents() {
+	start, end, events, err := loadEventsCache()
+	if err != nil {
+		slog.Debug("No cached events", "error", err)
+		return
+	}
+
+	slog.Debug("Loaded " + strconv.Itoa(len(events)) + " cached event(s) between " + start.Format(time.RFC3339) + " and " + end.Format(time.RFC3339))
+	gcal.requestStartDate, gcal.requestEndDate, gcal.eventsBuffer = start, end, events
+	gcal.calendarBuffers = make(map[string][]event)
+	for _, event := range events {
+		gcal.calendarBuffers[event.calendarId] = append(gcal.calendarBuffers[event.calendarId], event)
+	}
+}
+
+// Gets the events of one calendar between start and end
+func (gcal *googleCalendar) retrieveCalendarEvents(calendarId string, start time.Time, end time.Time) ([]event, error) {
+	slog.Info("Retrieving events between " + start.Format(time.RFC3339) + " and " + end.Format(time.RFC3339) + " for calendarId = " + calendarId)
+	var items []*calendar.Event
+	var summary, timeZone string
+	pages := 0
+	// busy calendars don't fit in a single page so all of them are retrieved
+	err := gcal.service.Events.List(calendarId).
 		SingleEvents(true).
-		TimeMin(gcal.requestStartDate.Format(time.RFC3339)).
-		TimeMax(gcal.requestEndDate.Format(time.RFC3339)).
+		TimeMin(start.Format(time.RFC3339)).
+		TimeMax(end.Format(time.RFC3339)).
 		OrderBy("startTime").
-		Fields("etag", "nextPageToken", "summary", "timeZone", "items(attendees, created, updated, description, start, end, etag, eventType, hangoutLink, htmlLink, id, location, status, summary, transparency)").
-		Do()
+		Fields("etag", "nextPageToken", "summary", "timeZone", "items("+eventFields+")").
+		Pages(context.Background(), func(page *calendar.Events) error {
+			items = append(items, page.Items...)
+			summary = page.Summary
+			timeZone = page.TimeZone
+			pages++
+			return nil
+		})
 
 	if err == nil {
-		slog.Debug("Retrieved " + strconv.Itoa(len(response.Items)) + " event(s) successfully")
+		slog.Debug("Retrieved " + strconv.Itoa(len(items)) + " event(s) in " + strconv.Itoa(pages) + " page(s) successfully")
 	} else {
+		return nil, err
+	}
+
+	events, err := convertEvents(items, summary, timeZone)
+	if err != nil {
+		return nil, err
+	}
+	for pos := range events {
+		events[pos].calendarId = calendarId
+	}
+
+	return events, nil
+}
+
+// Changes the response of the user to an invitation. The whole list of attendees is sent since patching replaces it.
+// The buffered event is replaced with the updated one so that it shows without retrieving all the events again
+func (gcal *googleCalendar) respond(target *event, response responseStatus) error {
+	calendarId := target.calendarId
+	if calendarId == "" {
+		calendarId = "primary"
+	}
+	slog.Info("Responding " + string(response) + " to event " + target.id)
+	item, err := gcal.service.Events.Get(calendarId, target.id).Fields("attendees").Do()
+	if err != nil {
+		return err
+	}
+
+	invited := false
+	for _, attendee := range item.Attendees {
+		if attendee.Self {
+			attendee.ResponseStatus = string(response)
+			invited = true
+		}
+	}
+	if !invited {
+		return errors.New(message("not-an-attendee") + target.id)
+	}
+
+	updated, err := gcal.service.Events.Patch(calendarId, target.id, &calendar.Event{Attendees: item.Attendees}).Fields(eventFields).Do()
+	if isInsufficientScopeError(err) {
+		// tokens granted before responding was possible can only read the events
+		return errors.New(message("reconnect-to-respond"))
+	}
+	if err != nil {
+		return err
+	}
+	events, err := convertEvents([]*calendar.Event{updated}, target.source, "")
+	if err != nil {
 		return err
 	}
+	if len(events) == 0 {
+		return fmt.Errorf(message("event-unavailable"), target.id)
+	}
+	events[0].calendarId = target.calendarId
+	for _, buffer := range [][]event{gcal.eventsBuffer, gcal.calendarBuffers[target.calendarId]} {
+		for pos := range buffer {
+			if buffer[pos].id == target.id {
+				buffer[pos] = events[0]
+			}
+		}
+	}
+	saveEventsCache(gcal.requestStartDate, gcal.requestEndDate, gcal.eventsBuffer)
+
+	return nil
+}
+
+// Whether Google refused the call because the token lacks the scope it needs, like tokens granted before the list of
+// calendars was read
+func isInsufficientScopeError(err error) bool {
+	var apiError *googleapi.Error
+	if !errors.As(err, &apiError) || apiError.Code != http.StatusForbidden {
+		return false
+	}
+	for _, item := range apiError.Errors {
+		if item.Reason == "insufficientPermissions" {
+			return true
+		}
+	}
+
+	return strings.Contains(apiError.Message, "insufficient authentication scopes")
+}
+
+// Whether the error is a server error, a timeout or a reset connection, which usually go away on their own
+func isTransientError(err error) bool {
+	var apiError *googleapi.Error
+	var urlError *url.Error
+	switch {
+	case errors.As(err, &apiError):
+		return apiError.Code >= http.StatusInternalServerError
+	case errors.As(err, &urlError):
+		return urlError.Timeout() || errors.Is(urlError.Err, syscall.ECONNRESET)
+	default:
+		return false
+	}
+}
+
+// Gets the calendars in the calendar-id preference, which can have several separated by commas
+func calendarIds() []string {
+	var result []string
+	for _, calendarId := range strings.Split(dailyApp.Preferences().StringWithFallback("calendar-id", "primary"), ",") {
+		if calendarId = strings.TrimSpace(calendarId); calendarId != "" && !slices.Contains(result, calendarId) {
+			result = append(result, calendarId)
+		}
+	}
+	if len(result) == 0 {
+		result = []string{"primary"}
+	}
 
-	var allEvents []event
-	for _, item := range response.Items {
+	return result
+}
+
+// Merges the events of the calendars sorted by start. Events that are in several calendars, like invitations to a
+// shared calendar, are only kept from the first one
+func mergeCalendarEvents(calendarIds []string, calendarBuffers map[string][]event) []event {
+	var result []event
+	seen := make(map[string]bool)
+	for _, calendarId := range calendarIds {
+		for _, event := range calendarBuffers[calendarId] {
+			if !seen[event.id] {
+				seen[event.id] = true
+				result = append(result, event)
+			}
+		}
+	}
+	slices.SortStableFunc(result, func(one event, other event) int { return one.start.Compare(other.start) })
+
+	return result
+}
+
+// Converts the events from Google Calendar, skipping cancelled ones and keeping only the latest version of each
+// instance of recurring events. Times without an offset are in the calendar's time zone unless the event has its own
+func convertEvents(items []*calendar.Event, source string, timeZone string) ([]event, error) {
+	var result []event
+	// the instance key of each event in the result
+	var instanceKeys []string
+	// positions in the result of the instances of recurring events, to detect duplicated instances
+	instancePositions := make(map[string]int)
+	cancelledInstances := make(map[string]bool)
+	for _, item := range items {
+		key := instanceKey(item)
+		if item.Status == "cancelled" {
+			slog.Debug("Ignoring cancelled event " + item.Id)
+			cancelledInstances[key] = true
+			continue
+		}
 		if item.Start.DateTime != "" {
 			//for now, ignore day events
-			eventStart, err := time.Parse(time.RFC3339, item.Start.DateTime)
+			eventStart, err := parseEventTime(item.Start, timeZone)
 			if err != nil {
-				return err
+				return nil, err
 			}
 
-			eventEnd, err := time.Parse(time.RFC3339, item.End.DateTime)
+			eventEnd, err := parseEventTime(item.End, timeZone)
 			if err != nil {
-				return err
+				return nil, err
+			}
+
+			eventUpdated, err := time.Parse(time.RFC3339, item.Updated)
+			if err != nil {
+				return nil, err
 			}
 
 			var selfResponse responseStatus
@@ -256,22 +818,232 @@ func (gcal *googleCalendar) retrieveEventsAround(day time.Time) error {
 			}
 
 			newEvent := event{
+				id:         item.Id,
 				title:      item.Summary,
 				start:      eventStart,
 				end:        eventEnd,
 				details:    item.Description,
-				notifiable: selfResponse != "declined" && item.Transparency != "transparent",
+				notifiable: isNotifiableResponse(selfResponse) && item.Transparency != "transparent",
 				response:   selfResponse,
+				htmlLink:   item.HtmlLink,
+				updated:    eventUpdated,
+				source:     source,
+				kind:       googleSource,
+				reminders:  extractReminders(item.Reminders),
+				organizer:  item.Organizer != nil && item.Organizer.Self,
+				recurring:  item.RecurringEventId != "",
+				free:       item.Transparency == "transparent",
+				tags:       extractTags(item.Description),
+			}
+			for _, itemAttachment := range item.Attachments {
+				newEvent.attachments = append(newEvent.attachments, attachment{title: itemAttachment.Title, url: itemAttachment.FileUrl})
 			}
-			if item.HangoutLink != "" {
-				newEvent.location = item.HangoutLink
-			} else {
-				newEvent.location = item.Location
+			newEvent.location = extractMeetingLocation(item)
+			newEvent.dialIn = extractDialIn(item.ConferenceData, item.Description)
+			if newEvent.dialIn.url == "" && newEvent.isVideoMeeting() {
+				newEvent.dialIn.url = newEvent.location
+			}
+
+			if pos, found := instancePositions[key]; found {
+				// a modified instance replaces the original one of the series
+				if newEvent.updated.After(result[pos].updated) {
+					result[pos] = newEvent
+				}
+				continue
 			}
-			allEvents = append(allEvents, newEvent)
+			instancePositions[key] = len(result)
+			result = append(result, newEvent)
+			instanceKeys = append(instanceKeys, key)
 		}
 	}
-	gcal.eventsBuffer = allEvents
 
-	return nil
+	var uncancelled []event
+	for pos := range result {
+		if !cancelledInstances[instanceKeys[pos]] {
+			uncancelled = append(uncancelled, result[pos])
+		}
+	}
+	// modified instances might have moved
+	slices.SortStableFunc(uncancelled, func(one event, other event) int { return one.start.Compare(other.start) })
+
+	return uncancelled, nil
+}
+
+// Parses the time of an event. Floating times (without an offset) are interpreted in the time zone of the event, the
+// calendar's if the event has none or the local one if neither is known
+func parseEventTime(dateTime *calendar.EventDateTime, calendarTimeZone string) (time.Time, error) {
+	result, err := time.Parse(time.RFC3339, dateTime.DateTime)
+	if err == nil {
+		return result, nil
+	}
+
+	location := time.Local
+	for _, timeZone := range []string{dateTime.TimeZone, calendarTimeZone} {
+		if timeZone == "" {
+			continue
+		}
+		zoneLocation, zoneErr := time.LoadLocation(timeZone)
+		if zoneErr == nil {
+			location = zoneLocation
+			break
+		}
+		slog.Warn("Unknown time zone " + timeZone)
+	}
+
+	result, floatingErr := time.ParseInLocation("2006-01-02T15:04:05", dateTime.DateTime, location)
+	if floatingErr != nil {
+		// the original error is more meaningful when it's not a floating time either
+		return time.Time{}, err
+	}
+	slog.Debug("Interpreting floating time " + dateTime.DateTime + " in " + location.String())
+
+	return result, nil
+}
+
+// Identifies an event or, for recurring events, the particular instance of the series so that modified instances can
+// be matched to the original ones
+func instanceKey(item *calendar.Event) string {
+	if item.RecurringEventId == "" || item.OriginalStartTime == nil {
+		return item.Id
+	}
+
+	originalStart := item.OriginalStartTime.DateTime + item.OriginalStartTime.Date
+	if parsed, err := time.Parse(time.RFC3339, item.OriginalStartTime.DateTime); err == nil {
+		// the same time can come with different offsets
+		originalStart = parsed.UTC().Format(time.RFC3339)
+	}
+
+	return item.RecurringEventId + "@" + originalStart
+}
+
+// Whether the user wants to be notified of events they responded to this way, based on the notify-responses
+// preference. Events without attendees are the user's own so they count as accepted
+func isNotifiableResponse(response responseStatus) bool {
+	switch dailyApp.Preferences().StringWithFallback("notify-responses", "all") {
+	case "accepted":
+		return response == accepted || response == empty
+	case "accepted-tentative":
+		return response == accepted || response == tentative || response == empty
+	default:
+		return response != declined
+	}
+}
+
+// Gets the minutes before the start of the popup reminders that override the calendar's default ones. Empty if the
+// event uses the default reminders
+func extractReminders(reminders *calendar.EventReminders) []int {
+	if reminders == nil || reminders.UseDefault {
+		return nil
+	}
+
+	var result []int
+	for _, reminder := range reminders.Overrides {
+		if reminder.Method == "popup" {
+			result = append(result, int(reminder.Minutes))
+		}
+	}
+
+	return result
+}
+
+// Gets where the event takes place: the link to join it if it's a meeting or its location otherwise. Video links are
+// preferred: from the conference data, then the Google Meet link and then links of known providers in the description.
+// A conference that can only be joined by SIP or phone comes after those since add-ons like Teams' sometimes only put
+// the dial-in in the conference data
+func extractMeetingLocation(item *calendar.Event) string {
+	conferenceUri := extractConferenceUri(item.ConferenceData)
+	if strings.HasPrefix(conferenceUri, "https://") || strings.HasPrefix(conferenceUri, "http://") {
+		return conferenceUri
+	}
+	if item.HangoutLink != "" {
+		return item.HangoutLink
+	}
+	if link := findMeetingLink(item.Description); link != "" {
+		return link
+	}
+	if conferenceUri != "" {
+		return conferenceUri
+	}
+
+	return item.Location
+}
+
+// Gets the first link of a known meeting provider in the text, if any
+func findMeetingLink(text string) string {
+	for _, provider := range meetingProviders {
+		if link := provider.linkRegex.FindString(text); link != "" {
+			slog.Debug("Found " + provider.name + " link in the description")
+			// links at the end of a sentence don't include the punctuation
+			return html.UnescapeString(strings.TrimRight(link, ".,;)"))
+		}
+	}
+
+	return ""
+}
+
+// Gets the best entry point to join a conference. Video entry points (e.g. Zoom, Meet, Webex) are preferred, then SIP
+// and finally phone
+func extractConferenceUri(conference *calendar.ConferenceData) string {
+	if conference == nil {
+		return ""
+	}
+
+	for _, entryPointType := range []string{"video", "sip", "phone"} {
+		for _, entryPoint := range conference.EntryPoints {
+			if entryPoint.EntryPointType == entryPointType && entryPoint.Uri != "" {
+				return entryPoint.Uri
+			}
+		}
+	}
+
+	return ""
+}
+
+// Gets the video link, phone number and PIN of a conference. Anything missing from the conference data is looked for in
+// the details of the event
+func extractDialIn(conference *calendar.ConferenceData, details string) dialIn {
+	result := parseDialIn(cleanEventDetails(details))
+	if conference == nil {
+		return result
+	}
+
+	foundPhone := false
+	for _, entryPoint := range conference.EntryPoints {
+		switch {
+		case entryPoint.EntryPointType == "video" && result.url == "":
+			result.url = entryPoint.Uri
+		case entryPoint.EntryPointType == "phone" && !foundPhone:
+			// conferences can have several numbers, the first one is the main one
+			foundPhone = true
+			if entryPoint.Label != "" {
+				result.phone = entryPoint.Label
+			} else if entryPoint.Uri != "" {
+				result.phone = strings.TrimPrefix(entryPoint.Uri, "tel:")
+			}
+			if entryPoint.Pin != "" {
+				result.pin = entryPoint.Pin
+			}
+		}
+	}
+
+	return result
+}
+
+func startOfDay(day time.Time) time.Time {
+	year, month, dayOfMonth := day.Date()
+	return time.Date(year, month, dayOfMonth, 0, 0, 0, 0, day.Location())
+}
+
+func minTime(one time.Time, other time.Time) time.Time {
+	if one.Before(other) {
+		return one
+	}
+	return other
+}
+
+func maxTime(one time.Time, other time.Time) time.Time {
+	if one.After(other) {
+		return one
+	}
+	return other
 }
@@ -0,0 +1,810 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"syscall"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+	"golang.org/x/oauth2"
+	"google.golang.org/api/calendar/v3"
+	"google.golang.org/api/googleapi"
+	"google.golang.org/api/option"
+)
+
+type conferenceTest struct {
+	entryPoints []*calendar.EntryPoint
+	expectedUri string
+}
+
+func TestExtractConferenceUri(t *testing.T) {
+	var conferenceTests = []conferenceTest{
+		{nil, ""},
+		{[]*calendar.EntryPoint{{EntryPointType: "video", Uri: "https://meet.google.com/abc"}}, "https://meet.google.com/abc"},
+		{[]*calendar.EntryPoint{{EntryPointType: "video", Uri: "https://company.webex.com/meet/1234"}}, "https://company.webex.com/meet/1234"},
+		{[]*calendar.EntryPoint{{EntryPointType: "phone", Uri: "tel:+1-555-1234"}, {EntryPointType: "video", Uri: "https://zoom.us/j/1234"}}, "https://zoom.us/j/1234"},
+		{[]*calendar.EntryPoint{{EntryPointType: "phone", Uri: "tel:+1-555-1234"}, {EntryPointType: "sip", Uri: "sip:1234@zoomcrc.com"}}, "sip:1234@zoomcrc.com"},
+		{[]*calendar.EntryPoint{{EntryPointType: "more", Uri: "https://tel.meet/abc"}, {EntryPointType: "phone", Uri: "tel:+1-555-1234"}}, "tel:+1-555-1234"},
+		{[]*calendar.EntryPoint{{EntryPointType: "video", Uri: ""}, {EntryPointType: "sip", Uri: "sip:1234@webex.com"}}, "sip:1234@webex.com"},
+		{[]*calendar.EntryPoint{{EntryPointType: "more", Uri: "https://tel.meet/abc"}}, ""},
+	}
+
+	for i, test := range conferenceTests {
+		var conference *calendar.ConferenceData
+		if test.entryPoints != nil {
+			conference = &calendar.ConferenceData{EntryPoints: test.entryPoints}
+		}
+		if actual := extractConferenceUri(conference); actual != test.expectedUri {
+			t.Errorf("%d. Actual %q doesn't match expected %q", i, actual, test.expectedUri)
+		}
+	}
+}
+
+type authorizationCodeTest struct {
+	pasted        string
+	expectedCode  string
+	expectedError bool
+}
+
+func TestParseAuthorizationCode(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	const state = "1234abcd"
+	var authorizationCodeTests = []authorizationCodeTest{
+		{"4/0AbCdEf", "4/0AbCdEf", false},
+		{"  4/0AbCdEf\n", "4/0AbCdEf", false},
+		{"http://localhost:41234/callback?state=1234abcd&code=4/0AbCdEf&scope=calendar", "4/0AbCdEf", false},
+		{"http://localhost:41234/callback?state=other&code=4/0AbCdEf", "", true},
+		{"", "", true},
+	}
+
+	for i, test := range authorizationCodeTests {
+		actual, err := parseAuthorizationCode(test.pasted, state)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%d. Actual error %v doesn't match expected error = %t", i, err, test.expectedError)
+		}
+		if actual != test.expectedCode {
+			t.Errorf("%d. Actual %q doesn't match expected %q. Original was %q", i, actual, test.expectedCode, test.pasted)
+		}
+	}
+}
+
+type remindersTest struct {
+	reminders         *calendar.EventReminders
+	expectedReminders []int
+}
+
+func TestExtractReminders(t *testing.T) {
+	var remindersTests = []remindersTest{
+		{nil, nil},
+		{&calendar.EventReminders{UseDefault: true}, nil},
+		{&calendar.EventReminders{UseDefault: false}, nil},
+		{&calendar.EventReminders{Overrides: []*calendar.EventReminder{{Method: "popup", Minutes: 10}}}, []int{10}},
+		{&calendar.EventReminders{Overrides: []*calendar.EventReminder{{Method: "popup", Minutes: 30}, {Method: "popup", Minutes: 5}}}, []int{30, 5}},
+		{&calendar.EventReminders{Overrides: []*calendar.EventReminder{{Method: "email", Minutes: 60}, {Method: "popup", Minutes: 0}}}, []int{0}},
+		{&calendar.EventReminders{Overrides: []*calendar.EventReminder{{Method: "email", Minutes: 60}}}, nil},
+	}
+
+	for i, test := range remindersTests {
+		if actual := extractReminders(test.reminders); !slices.Equal(actual, test.expectedReminders) {
+			t.Errorf("%d. Actual %v doesn't match expected %v", i, actual, test.expectedReminders)
+		}
+	}
+}
+
+type dialInTest struct {
+	entryPoints    []*calendar.EntryPoint
+	details        string
+	expectedDialIn dialIn
+}
+
+func TestExtractDialIn(t *testing.T) {
+	var dialInTests = []dialInTest{
+		{nil, "", dialIn{}},
+		{nil, "Call +1 555-123-4567<br>PIN: 9876", dialIn{phone: "+1 555-123-4567", pin: "9876"}},
+		{[]*calendar.EntryPoint{{EntryPointType: "video", Uri: "https://meet.google.com/abc"}}, "", dialIn{url: "https://meet.google.com/abc"}},
+		{[]*calendar.EntryPoint{{EntryPointType: "video", Uri: "https://meet.google.com/abc"}, {EntryPointType: "phone", Uri: "tel:+1-555-1234", Label: "+1 555-1234", Pin: "1234"}}, "", dialIn{url: "https://meet.google.com/abc", phone: "+1 555-1234", pin: "1234"}},
+		{[]*calendar.EntryPoint{{EntryPointType: "phone", Uri: "tel:+1-555-1234"}, {EntryPointType: "phone", Uri: "tel:+44-20-1234"}}, "", dialIn{phone: "+1-555-1234"}},
+		{[]*calendar.EntryPoint{{EntryPointType: "phone", Uri: "tel:+1-555-1234"}}, "Passcode 5555", dialIn{phone: "+1-555-1234", pin: "5555"}},
+	}
+
+	for i, test := range dialInTests {
+		var conference *calendar.ConferenceData
+		if test.entryPoints != nil {
+			conference = &calendar.ConferenceData{EntryPoints: test.entryPoints}
+		}
+		if actual := extractDialIn(conference, test.details); actual != test.expectedDialIn {
+			t.Errorf("%d. Actual %+v doesn't match expected %+v", i, actual, test.expectedDialIn)
+		}
+	}
+}
+
+func createCalendarEvent(id string, start time.Time, updated time.Time) *calendar.Event {
+	return &calendar.Event{
+		Id:      id,
+		Summary: id,
+		Start:   &calendar.EventDateTime{DateTime: start.Format(time.RFC3339)},
+		End:     &calendar.EventDateTime{DateTime: start.Add(30 * time.Minute).Format(time.RFC3339)},
+		Updated: updated.Format(time.RFC3339),
+	}
+}
+
+func createInstance(seriesId string, originalStart time.Time, start time.Time, updated time.Time) *calendar.Event {
+	result := createCalendarEvent(seriesId+"_"+originalStart.UTC().Format("20060102T150405Z"), start, updated)
+	result.Summary = seriesId
+	result.RecurringEventId = seriesId
+	result.OriginalStartTime = &calendar.EventDateTime{DateTime: originalStart.Format(time.RFC3339)}
+	return result
+}
+
+func TestConvertEventsWithRecurringExceptions(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	created := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+	modified := created.Add(24 * time.Hour)
+	monday := time.Date(2024, 11, 18, 10, 0, 0, 0, time.UTC)
+	tuesday := monday.AddDate(0, 0, 1)
+	wednesday := monday.AddDate(0, 0, 2)
+
+	moved := createInstance("standup", tuesday, wednesday.Add(3*time.Hour), modified)
+	moved.Summary = "moved standup"
+	cancelled := createInstance("standup", wednesday, wednesday, modified)
+	cancelled.Status = "cancelled"
+	items := []*calendar.Event{
+		createInstance("standup", monday, monday, created),
+		createInstance("standup", tuesday, tuesday, created),
+		createCalendarEvent("single", tuesday.Add(time.Hour), created),
+		createInstance("standup", wednesday, wednesday, created),
+		cancelled,
+		moved,
+	}
+
+	actual, err := convertEvents(items, "calendar", "UTC")
+	if err != nil {
+		t.Fatal("Error converting events", err)
+	}
+	expectedTitles := []string{"standup", "single", "moved standup"}
+	var actualTitles []string
+	for _, converted := range actual {
+		actualTitles = append(actualTitles, converted.title)
+	}
+	if !slices.Equal(actualTitles, expectedTitles) {
+		t.Fatalf("Actual %q doesn't match expected %q", actualTitles, expectedTitles)
+	}
+	if !actual[2].start.Equal(wednesday.Add(3 * time.Hour)) {
+		t.Errorf("Actual start %v of moved instance doesn't match expected %v", actual[2].start, wednesday.Add(3*time.Hour))
+	}
+}
+
+// A fake Google Calendar server that returns the events in the requested range and records the requests it receives
+type fakeCalendarServer struct {
+	*httptest.Server
+	items    []*calendar.Event
+	requests []url.Values
+	// events of calendars other than the primary one, by calendar ID
+	otherCalendars map[string][]*calendar.Event
+	// maximum number of items per page, or 0 to return all of them in a single one
+	pageSize int
+	// bodies of the patches to events
+	patches []*calendar.Event
+	// status codes to fail the next requests for events with
+	failures []int
+	// whether the token can only read the events
+	readOnly bool
+}
+
+// Starts a fake calendar server and makes the new calendar services connect to it instead of Google
+func startFakeCalendarServer(t *testing.T, items []*calendar.Event) *fakeCalendarServer {
+	result := &fakeCalendarServer{items: items}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/calendars/{id}/events", func(writer http.ResponseWriter, request *http.Request) {
+		query := request.URL.Query()
+		result.requests = append(result.requests, query)
+		if len(result.failures) > 0 {
+			http.Error(writer, "{}", result.failures[0])
+			result.failures = result.failures[1:]
+			return
+		}
+		timeMin, _ := time.Parse(time.RFC3339, query.Get("timeMin"))
+		timeMax, _ := time.Parse(time.RFC3339, query.Get("timeMax"))
+		response := calendar.Events{Summary: "fake"}
+		calendarItems := result.items
+		if id := request.PathValue("id"); id != "primary" {
+			calendarItems = result.otherCalendars[id]
+			response.Summary = id
+		}
+		for _, item := range calendarItems {
+			start, _ := time.Parse(time.RFC3339, item.Start.DateTime)
+			if !start.Before(timeMin) && start.Before(timeMax) {
+				response.Items = append(response.Items, item)
+			}
+		}
+		if result.pageSize > 0 {
+			first, _ := strconv.Atoi(query.Get("pageToken"))
+			last := min(first+result.pageSize, len(response.Items))
+			if last < len(response.Items) {
+				response.NextPageToken = strconv.Itoa(last)
+			}
+			response.Items = response.Items[first:last]
+		}
+		_ = json.NewEncoder(writer).Encode(response)
+	})
+	mux.HandleFunc("/calendars/primary/events/{eventId}", func(writer http.ResponseWriter, request *http.Request) {
+		pos := slices.IndexFunc(result.items, func(item *calendar.Event) bool { return item.Id == request.PathValue("eventId") })
+		if pos < 0 {
+			http.NotFound(writer, request)
+			return
+		}
+		if request.Method == http.MethodPatch && result.readOnly {
+			writer.WriteHeader(http.StatusForbidden)
+			_, _ = writer.Write([]byte(`{"error": {"code": 403, "message": "Request had insufficient authentication scopes.", "errors": [{"reason": "insufficientPermissions"}]}}`))
+			return
+		}
+		if request.Method == http.MethodPatch {
+			patch := &calendar.Event{}
+			_ = json.NewDecoder(request.Body).Decode(patch)
+			result.patches = append(result.patches, patch)
+			result.items[pos].Attendees = patch.Attendees
+		}
+		_ = json.NewEncoder(writer).Encode(result.items[pos])
+	})
+	mux.HandleFunc("/users/me/calendarList/primary", func(writer http.ResponseWriter, request *http.Request) {
+		_ = json.NewEncoder(writer).Encode(calendar.CalendarListEntry{
+			DefaultReminders: []*calendar.EventReminder{{Method: "popup", Minutes: 10}},
+		})
+	})
+	result.Server = httptest.NewServer(mux)
+	t.Cleanup(result.Close)
+
+	originalCachePath := eventsCachePath
+	cachePath := filepath.Join(t.TempDir(), "events-cache.json")
+	eventsCachePath = func() string { return cachePath }
+	t.Cleanup(func() { eventsCachePath = originalCachePath })
+
+	originalOptions := calendarServiceOptions
+	calendarServiceOptions = func(token string) ([]option.ClientOption, error) {
+		return []option.ClientOption{option.WithHTTPClient(result.Client()), option.WithEndpoint(result.URL + "/")}, nil
+	}
+	t.Cleanup(func() { calendarServiceOptions = originalOptions })
+
+	return result
+}
+
+func TestGoogleCalendarGetEvents(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("calendar-id", "primary")
+	dailyApp.Preferences().SetString("calendar-token", `{"access_token": "fake"}`)
+
+	day := time.Date(2024, 11, 18, 0, 0, 0, 0, time.Local)
+	created := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+	server := startFakeCalendarServer(t, []*calendar.Event{
+		createCalendarEvent("today", day.Add(10*time.Hour), created),
+		createCalendarEvent("tomorrow", day.AddDate(0, 0, 1).Add(10*time.Hour), created),
+		createCalendarEvent("next week", day.AddDate(0, 0, 7).Add(10*time.Hour), created),
+	})
+
+	source, err := newGoogleCalendarEventSource()
+	if err != nil {
+		t.Fatal("Error creating event source", err)
+	}
+	if actual := dailyApp.Preferences().Int("calendar-default-reminder"); actual != 10 {
+		t.Errorf("Actual default reminder %d doesn't match expected 10", actual)
+	}
+
+	events, refreshed, err := source.getEvents(day, false)
+	if err != nil {
+		t.Fatal("Error getting events", err)
+	}
+	if !refreshed || len(server.requests) != 1 {
+		t.Fatalf("Expected a single retrieval with an empty buffer but got %d", len(server.requests))
+	}
+	if len(events) != 1 || events[0].title != "today" || events[0].source != "fake" {
+		t.Errorf("Actual events %v don't match expected [today]", events)
+	}
+	if len(source.getBufferedEvents()) != 2 {
+		t.Errorf("Actual buffered events %d don't match expected 2", len(source.getBufferedEvents()))
+	}
+
+	// within the buffer thresholds
+	events, refreshed, _ = source.getEvents(day.AddDate(0, 0, 1), false)
+	if refreshed || len(server.requests) != 1 {
+		t.Errorf("Expected the buffer to be used but got %d retrievals", len(server.requests))
+	}
+	if len(events) != 1 || events[0].title != "tomorrow" {
+		t.Errorf("Actual events %v don't match expected [tomorrow]", events)
+	}
+
+	// close to the buffer end
+	_, refreshed, _ = source.getEvents(day.AddDate(0, 0, 4), false)
+	if !refreshed || len(server.requests) != 2 {
+		t.Fatalf("Expected the buffer to be extended but got %d retrievals", len(server.requests))
+	}
+	expectedEnd := day.AddDate(0, 0, defaultBufferDays+5).Format(time.RFC3339)
+	if actual := server.requests[1].Get("timeMax"); actual != expectedEnd {
+		t.Errorf("Actual extended end %s doesn't match expected %s", actual, expectedEnd)
+	}
+	if len(source.getBufferedEvents()) != 3 {
+		t.Errorf("Actual buffered events %d don't match expected 3", len(source.getBufferedEvents()))
+	}
+
+	_, refreshed, _ = source.getEvents(day.AddDate(0, 0, 4), true)
+	if !refreshed || len(server.requests) != 3 {
+		t.Errorf("Expected a forced retrieval but got %d retrievals", len(server.requests))
+	}
+}
+
+func TestGoogleCalendarKeepsRangeAfterFailedRetrieval(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("calendar-id", "primary")
+	dailyApp.Preferences().SetString("calendar-token", `{"access_token": "fake"}`)
+
+	day := time.Date(2024, 11, 18, 0, 0, 0, 0, time.Local)
+	created := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+	server := startFakeCalendarServer(t, []*calendar.Event{
+		createCalendarEvent("today", day.Add(10*time.Hour), created),
+		createCalendarEvent("next week", day.AddDate(0, 0, 7).Add(10*time.Hour), created),
+	})
+
+	source, err := newGoogleCalendarEventSource()
+	if err != nil {
+		t.Fatal("Error creating event source", err)
+	}
+	if _, _, err := source.getEvents(day, false); err != nil {
+		t.Fatal("Error getting events", err)
+	}
+	start, end := source.requestStartDate, source.requestEndDate
+
+	// close to the buffer end
+	server.failures = []int{http.StatusBadRequest}
+	if _, _, err := source.getEvents(day.AddDate(0, 0, 4), false); err == nil {
+		t.Fatal("Expected the failed retrieval to return an error")
+	}
+	if !source.requestStartDate.Equal(start) || !source.requestEndDate.Equal(end) {
+		t.Errorf("Actual range %v - %v changed from %v - %v after a failed retrieval", source.requestStartDate, source.requestEndDate, start, end)
+	}
+
+	_, refreshed, err := source.getEvents(day.AddDate(0, 0, 4), false)
+	if err != nil || !refreshed || len(source.getBufferedEvents()) != 2 {
+		t.Errorf("Actual %d buffered events (refreshed = %t, error %v) were not extended on the next attempt", len(source.getBufferedEvents()), refreshed, err)
+	}
+}
+
+func TestGoogleCalendarGetEventsPaginated(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("calendar-id", "primary")
+	dailyApp.Preferences().SetString("calendar-token", `{"access_token": "fake"}`)
+
+	day := time.Date(2024, 11, 18, 0, 0, 0, 0, time.Local)
+	created := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+	var items []*calendar.Event
+	for hour := 8; hour < 13; hour++ {
+		items = append(items, createCalendarEvent("event"+strconv.Itoa(hour), day.Add(time.Duration(hour)*time.Hour), created))
+	}
+	server := startFakeCalendarServer(t, items)
+	server.pageSize = 2
+
+	source, err := newGoogleCalendarEventSource()
+	if err != nil {
+		t.Fatal("Error creating event source", err)
+	}
+	events, _, err := source.getEvents(day, false)
+	if err != nil {
+		t.Fatal("Error getting events", err)
+	}
+	if len(server.requests) != 3 {
+		t.Errorf("Actual %d requests don't match the expected 3 pages", len(server.requests))
+	}
+	if len(events) != len(items) {
+		t.Errorf("Actual %d events don't match expected %d", len(events), len(items))
+	}
+}
+
+type eventTimeTest struct {
+	dateTime         calendar.EventDateTime
+	calendarTimeZone string
+	expected         time.Time
+	expectedError    bool
+}
+
+func TestParseEventTime(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
+	toronto, _ := time.LoadLocation("America/Toronto")
+	var eventTimeTests = []eventTimeTest{
+		{calendar.EventDateTime{DateTime: "2024-11-18T10:00:00-05:00"}, "Europe/Paris", time.Date(2024, 11, 18, 15, 0, 0, 0, time.UTC), false},
+		{calendar.EventDateTime{DateTime: "2024-11-18T10:00:00", TimeZone: "America/Toronto"}, "Europe/Paris", time.Date(2024, 11, 18, 10, 0, 0, 0, toronto), false},
+		{calendar.EventDateTime{DateTime: "2024-11-18T10:00:00"}, "Europe/Paris", time.Date(2024, 11, 18, 10, 0, 0, 0, paris), false},
+		{calendar.EventDateTime{DateTime: "2024-11-18T10:00:00", TimeZone: "Nowhere/Unknown"}, "Europe/Paris", time.Date(2024, 11, 18, 10, 0, 0, 0, paris), false},
+		{calendar.EventDateTime{DateTime: "2024-11-18T10:00:00"}, "", time.Date(2024, 11, 18, 10, 0, 0, 0, time.Local), false},
+		{calendar.EventDateTime{DateTime: "tomorrow"}, "Europe/Paris", time.Time{}, true},
+	}
+
+	for i, test := range eventTimeTests {
+		actual, err := parseEventTime(&test.dateTime, test.calendarTimeZone)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%d. Actual error %v doesn't match expected error = %t", i, err, test.expectedError)
+		}
+		if !actual.Equal(test.expected) {
+			t.Errorf("%d. Actual %v doesn't match expected %v", i, actual, test.expected)
+		}
+	}
+}
+
+func TestConvertEventsWithFloatingTime(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	created := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+	floating := createCalendarEvent("floating", created, created)
+	floating.Start.DateTime = "2024-11-18T10:00:00"
+	floating.End.DateTime = "2024-11-18T10:30:00"
+	items := []*calendar.Event{createCalendarEvent("zoned", time.Date(2024, 11, 18, 8, 0, 0, 0, time.UTC), created), floating}
+
+	actual, err := convertEvents(items, "calendar", "Asia/Tokyo")
+	if err != nil {
+		t.Fatal("Error converting events", err)
+	}
+	tokyo, _ := time.LoadLocation("Asia/Tokyo")
+	expectedStart := time.Date(2024, 11, 18, 10, 0, 0, 0, tokyo)
+	if len(actual) != 2 || actual[0].title != "floating" || !actual[0].start.Equal(expectedStart) {
+		t.Fatalf("Actual events %v don't start with the floating one at %v", actual, expectedStart)
+	}
+	if actual[0].kind != googleSource {
+		t.Errorf("Actual kind %q of event doesn't match expected %q", actual[0].kind, googleSource)
+	}
+	if actual[0].end.Sub(actual[0].start) != 30*time.Minute {
+		t.Errorf("Actual duration %v of floating event doesn't match expected 30m", actual[0].end.Sub(actual[0].start))
+	}
+}
+
+func TestConvertEventsShownAsFree(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	created := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+	focus := createCalendarEvent("focus", time.Date(2024, 11, 18, 8, 0, 0, 0, time.UTC), created)
+	focus.Transparency = "transparent"
+	items := []*calendar.Event{focus, createCalendarEvent("review", time.Date(2024, 11, 18, 9, 0, 0, 0, time.UTC), created)}
+
+	actual, err := convertEvents(items, "calendar", "UTC")
+	if err != nil {
+		t.Fatal("Error converting events", err)
+	}
+	if len(actual) != 2 || !actual[0].free || actual[0].notifiable || actual[1].free {
+		t.Errorf("Actual events %+v don't match expected only the transparent one free", actual)
+	}
+}
+
+// A token source that returns the tokens in order, repeating the last one
+type fakeTokenSource struct {
+	tokens []*oauth2.Token
+}
+
+func (fake *fakeTokenSource) Token() (*oauth2.Token, error) {
+	result := fake.tokens[0]
+	if len(fake.tokens) > 1 {
+		fake.tokens = fake.tokens[1:]
+	}
+	return result, nil
+}
+
+func TestPersistingTokenSource(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("calendar-token", "original")
+
+	expiry := time.Date(2024, 11, 18, 9, 0, 0, 0, time.UTC)
+	stored := &oauth2.Token{AccessToken: "first", Expiry: expiry}
+	refreshed := &oauth2.Token{AccessToken: "second", RefreshToken: "refresh", Expiry: expiry.Add(time.Hour)}
+	source := &persistingTokenSource{source: &fakeTokenSource{tokens: []*oauth2.Token{stored, refreshed}}, last: stored, preference: "calendar-token"}
+
+	_, _ = source.Token()
+	if actual := dailyApp.Preferences().String("calendar-token"); actual != "original" {
+		t.Errorf("Actual token %q was stored without changing", actual)
+	}
+
+	_, _ = source.Token()
+	var actual oauth2.Token
+	err := json.Unmarshal([]byte(dailyApp.Preferences().String("calendar-token")), &actual)
+	if err != nil || actual.AccessToken != "second" || actual.RefreshToken != "refresh" || !actual.Expiry.Equal(refreshed.Expiry) {
+		t.Errorf("Actual stored token %+v doesn't match the refreshed one", actual)
+	}
+}
+
+func TestCreateCalendarOptions(t *testing.T) {
+	entries := []*calendar.CalendarListEntry{
+		{Id: "me@example.com", Summary: "me@example.com", Primary: true},
+		{Id: "family123@group.calendar.google.com", Summary: "Family"},
+		{Id: "team1@group.calendar.google.com", Summary: "Team"},
+		{Id: "team2@group.calendar.google.com", Summary: "Team"},
+		{Id: "untitled@group.calendar.google.com"},
+	}
+
+	names, ids := createCalendarOptions(entries)
+	expectedNames := []string{"me@example.com", "Family", "Team (team1@group.calendar.google.com)", "Team (team2@group.calendar.google.com)", "(untitled@group.calendar.google.com)"}
+	if !slices.Equal(names, expectedNames) {
+		t.Fatalf("Actual %q don't match expected %q", names, expectedNames)
+	}
+	expectedIds := []string{"primary", "family123@group.calendar.google.com", "team1@group.calendar.google.com", "team2@group.calendar.google.com", "untitled@group.calendar.google.com"}
+	for i, name := range names {
+		if ids[name] != expectedIds[i] {
+			t.Errorf("%d. Actual ID %q of %q doesn't match expected %q", i, ids[name], name, expectedIds[i])
+		}
+	}
+}
+
+type pickCalendarsTest struct {
+	calendarIds string
+	picked      []string
+	expected    string
+}
+
+func TestPickCalendars(t *testing.T) {
+	names := []string{"me@example.com", "Family", "Team"}
+	ids := map[string]string{"me@example.com": "primary", "Family": "family123", "Team": "team1"}
+	var pickCalendarsTests = []pickCalendarsTest{
+		{"primary", []string{"me@example.com", "Team"}, "primary, team1"},
+		{"primary, team1", []string{"Team"}, "team1"},
+		{"typed@example.com,family123", []string{"Family"}, "typed@example.com, family123"},
+		{"", nil, ""},
+	}
+	for i, test := range pickCalendarsTests {
+		if actual := pickCalendars(test.calendarIds, names, ids, test.picked); actual != test.expected {
+			t.Errorf("%d. Actual calendar IDs %q don't match expected %q", i, actual, test.expected)
+		}
+		if actual := findPickedCalendars(test.expected, names, ids); !slices.Equal(actual, test.picked) {
+			t.Errorf("%d. Actual picked calendars %q don't match expected %q", i, actual, test.picked)
+		}
+	}
+}
+
+type meetingLocationTest struct {
+	item             calendar.Event
+	expectedLocation string
+}
+
+func TestExtractMeetingLocation(t *testing.T) {
+	zoomConference := &calendar.ConferenceData{EntryPoints: []*calendar.EntryPoint{{EntryPointType: "video", Uri: "https://company.zoom.us/j/1234"}}}
+	teamsDialIn := &calendar.ConferenceData{EntryPoints: []*calendar.EntryPoint{{EntryPointType: "phone", Uri: "tel:+1-555-0100,,123456#"}}}
+	var meetingLocationTests = []meetingLocationTest{
+		{calendar.Event{ConferenceData: zoomConference, HangoutLink: "https://meet.google.com/abc-defg-hij", Location: "Room 1"}, "https://company.zoom.us/j/1234"},
+		{calendar.Event{HangoutLink: "https://meet.google.com/abc-defg-hij", Location: "Room 1"}, "https://meet.google.com/abc-defg-hij"},
+		{calendar.Event{Description: "Join Zoom Meeting\nhttps://us02web.zoom.us/j/81234567890?pwd=abc123\nMeeting ID: 812 3456 7890", Location: "Room 1"}, "https://us02web.zoom.us/j/81234567890?pwd=abc123"},
+		{calendar.Event{Description: `<a href="https://zoom.us/my/someone">Join</a>`}, "https://zoom.us/my/someone"},
+		{calendar.Event{Description: "Join at https://zoom.us/j/1234."}, "https://zoom.us/j/1234"},
+		{calendar.Event{Description: "Meet at https://meet.google.com/abc-defg-hij."}, "https://meet.google.com/abc-defg-hij"},
+		{calendar.Event{Description: `Microsoft Teams meeting<br><a href="https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0?context=%7b%22Tid%22%7d&amp;x=1">Click here to join</a>`, Location: "Microsoft Teams Meeting"}, "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc%40thread.v2/0?context=%7b%22Tid%22%7d&x=1"},
+		{calendar.Event{Description: "Join: https://teams.microsoft.com/meet/2345678?p=abc", Location: "Microsoft Teams Meeting"}, "https://teams.microsoft.com/meet/2345678?p=abc"},
+		{calendar.Event{Description: "Join: https://dod.teams.microsoft.us/l/meetup-join/19%3ameeting_abc"}, "https://dod.teams.microsoft.us/l/meetup-join/19%3ameeting_abc"},
+		{calendar.Event{ConferenceData: teamsDialIn, Description: "Join: https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc"}, "https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc"},
+		{calendar.Event{ConferenceData: teamsDialIn, Location: "Room 1"}, "tel:+1-555-0100,,123456#"},
+		{calendar.Event{ConferenceData: zoomConference, Description: "Join: https://teams.microsoft.com/l/meetup-join/19%3ameeting_abc"}, "https://company.zoom.us/j/1234"},
+		{calendar.Event{Description: "Join: https://company.webex.com/meet/jdoe", Location: "Webex"}, "https://company.webex.com/meet/jdoe"},
+		{calendar.Event{Description: "Join: https://company.webex.com/company/j.php?MTID=m1234"}, "https://company.webex.com/company/j.php?MTID=m1234"},
+		{calendar.Event{Description: "Agenda at https://docs.example.com/agenda", Location: "Room 1"}, "Room 1"},
+		{calendar.Event{Location: "https://zoom.us/j/1234"}, "https://zoom.us/j/1234"},
+		{calendar.Event{}, ""},
+	}
+
+	for i, test := range meetingLocationTests {
+		if actual := extractMeetingLocation(&test.item); actual != test.expectedLocation {
+			t.Errorf("%d. Actual %q doesn't match expected %q", i, actual, test.expectedLocation)
+		}
+	}
+}
+
+func TestGoogleCalendarGetEventsFromSeveralCalendars(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("calendar-id", "primary, personal")
+	dailyApp.Preferences().SetString("calendar-token", `{"access_token": "fake"}`)
+
+	day := time.Date(2024, 11, 18, 0, 0, 0, 0, time.Local)
+	created := time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC)
+	shared := createCalendarEvent("shared", day.Add(12*time.Hour), created)
+	server := startFakeCalendarServer(t, []*calendar.Event{
+		createCalendarEvent("standup", day.Add(9*time.Hour), created),
+		shared,
+	})
+	server.otherCalendars = map[string][]*calendar.Event{
+		"personal": {createCalendarEvent("dentist", day.Add(8*time.Hour), created), shared},
+	}
+
+	source, err := newGoogleCalendarEventSource()
+	if err != nil {
+		t.Fatal("Error creating event source", err)
+	}
+	events, _, err := source.getEvents(day, false)
+	if err != nil {
+		t.Fatal("Error getting events", err)
+	}
+
+	var actual []string
+	for _, event := range events {
+		actual = append(actual, event.title+"@"+event.calendarId)
+	}
+	expected := []string{"dentist@personal", "standup@primary", "shared@primary"}
+	if !slices.Equal(actual, expected) {
+		t.Errorf("Actual %q don't match expected %q", actual, expected)
+	}
+	if events[0].source != "personal" {
+		t.Errorf("Actual source %q doesn't match the calendar", events[0].source)
+	}
+}
+
+func TestGoogleCalendarRespond(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("calendar-id", "primary")
+	dailyApp.Preferences().SetString("calendar-token", `{"access_token": "fake"}`)
+
+	day := time.Date(2024, 11, 18, 0, 0, 0, 0, time.Local)
+	invitation := createCalendarEvent("review", day.Add(10*time.Hour), time.Date(2024, 11, 1, 9, 0, 0, 0, time.UTC))
+	invitation.Attendees = []*calendar.EventAttendee{
+		{Email: "boss@example.com", Organizer: true, ResponseStatus: "accepted"},
+		{Email: "me@example.com", Self: true, ResponseStatus: "needsAction"},
+	}
+	server := startFakeCalendarServer(t, []*calendar.Event{invitation, createCalendarEvent("focus", day.Add(14*time.Hour), day)})
+
+	source, err := newGoogleCalendarEventSource()
+	if err != nil {
+		t.Fatal("Error creating event source", err)
+	}
+	events, _, err := source.getEvents(day, false)
+	if err != nil {
+		t.Fatal("Error getting events", err)
+	}
+
+	err = source.respond(&events[0], declined)
+	if err != nil {
+		t.Fatal("Error responding", err)
+	}
+	if len(server.patches) != 1 || len(server.patches[0].Attendees) != 2 || server.patches[0].Attendees[1].ResponseStatus != "declined" {
+		t.Errorf("Actual patches %v don't change only the response of the user", server.patches)
+	}
+
+	events, refreshed, _ := source.getEvents(day, false)
+	if refreshed || len(server.requests) != 1 {
+		t.Errorf("Events were retrieved again after responding")
+	}
+	if events[0].response != declined || events[0].calendarId != "primary" {
+		t.Errorf("Actual response %q in calendar %q doesn't match the expected declined", events[0].response, events[0].calendarId)
+	}
+
+	if err := source.respond(&events[1], accepted); err == nil {
+		t.Errorf("Responded to an event without attendees")
+	}
+
+	server.readOnly = true
+	if err := source.respond(&events[0], accepted); err == nil || err.Error() != message("reconnect-to-respond") {
+		t.Errorf("Actual error %v doesn't ask to reconnect", err)
+	}
+}
+
+type bufferWindowTest struct {
+	days              int
+	threshold         int
+	expectedDays      int
+	expectedThreshold int
+}
+
+func TestBufferWindow(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var bufferWindowTests = []bufferWindowTest{
+		{14, 3, 14, 3},
+		{2, 1, 2, 1},
+		{1, 0, 1, 0},
+		{3, 3, defaultBufferDays, defaultBufferThreshold},
+		{0, 0, defaultBufferDays, defaultBufferThreshold},
+		{5, -1, defaultBufferDays, defaultBufferThreshold},
+		{5, -1, defaultBufferDays, defaultBufferThreshold},
+		{14, 3, 14, 3},
+	}
+
+	for i, test := range bufferWindowTests {
+		dailyApp.Preferences().SetInt("calendar-buffer-days", test.days)
+		dailyApp.Preferences().SetInt("calendar-buffer-threshold", test.threshold)
+		actualDays, actualThreshold := bufferWindow()
+		if actualDays != test.expectedDays || actualThreshold != test.expectedThreshold {
+			t.Errorf("%d. Actual %d/%d don't match expected %d/%d", i, actualDays, actualThreshold, test.expectedDays, test.expectedThreshold)
+		}
+	}
+}
+
+func TestRefreshRetriesTransientErrors(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("calendar-id", "primary")
+	dailyApp.Preferences().SetString("calendar-token", `{"access_token": "fake"}`)
+	// the retries are run by hand instead of waiting for them
+	originalDelay := transientRetryDelay
+	transientRetryDelay = time.Hour
+	defer func() { transientRetryDelay = originalDelay }()
+
+	day := time.Date(2024, 11, 18, 0, 0, 0, 0, time.Local)
+	displayDay = day
+	server := startFakeCalendarServer(t, []*calendar.Event{createCalendarEvent("today", day.Add(10*time.Hour), day)})
+	defer func() { eventSource, transientRetry = nil, 0 }()
+	defer cancelRetry()
+
+	server.failures = []int{http.StatusServiceUnavailable, http.StatusBadGateway, http.StatusInternalServerError, http.StatusInternalServerError}
+	for retry := 1; retry <= maxTransientRetries; retry++ {
+		refresh(true)
+		if transientRetry != retry || retryTimer == nil || retryDelay != 0 {
+			t.Errorf("%d. Actual retry %d or delay %s don't match the expected quick retry", retry, transientRetry, retryDelay)
+		}
+	}
+	refresh(true)
+	if transientRetry != 0 || retryDelay != minRetryDelay || len(server.requests) != 1+maxTransientRetries {
+		t.Errorf("Actual retry %d, delay %s after %d requests don't match the expected reported error", transientRetry, retryDelay, len(server.requests))
+	}
+
+	server.requests = nil
+	server.failures = []int{http.StatusNotFound}
+	if refresh(true); transientRetry != 0 || len(server.requests) != 1 {
+		t.Errorf("Permanent error was retried with %d requests", len(server.requests))
+	}
+}
+
+type insufficientScopeErrorTest struct {
+	err      error
+	expected bool
+}
+
+func TestIsInsufficientScopeError(t *testing.T) {
+	var insufficientScopeErrorTests = []insufficientScopeErrorTest{
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "insufficientPermissions"}}}, true},
+		{fmt.Errorf("listing: %w", &googleapi.Error{Code: http.StatusForbidden, Message: "Request had insufficient authentication scopes."}), true},
+		{&googleapi.Error{Code: http.StatusForbidden, Errors: []googleapi.ErrorItem{{Reason: "rateLimitExceeded"}}}, false},
+		{&googleapi.Error{Code: http.StatusUnauthorized}, false},
+		{nil, false},
+	}
+
+	for i, test := range insufficientScopeErrorTests {
+		if actual := isInsufficientScopeError(test.err); actual != test.expected {
+			t.Errorf("%d. Actual %t for %v doesn't match expected %t", i, actual, test.err, test.expected)
+		}
+	}
+}
+
+type transientErrorTest struct {
+	err      error
+	expected bool
+}
+
+func TestIsTransientError(t *testing.T) {
+	var transientErrorTests = []transientErrorTest{
+		{&googleapi.Error{Code: http.StatusServiceUnavailable}, true},
+		{fmt.Errorf("listing: %w", &googleapi.Error{Code: http.StatusBadGateway}), true},
+		{&googleapi.Error{Code: http.StatusNotFound}, false},
+		{&googleapi.Error{Code: http.StatusGone}, false},
+		{&url.Error{Op: "Get", Err: &net.OpError{Op: "read", Err: syscall.ECONNRESET}}, true},
+		{&url.Error{Op: "Get", Err: context.DeadlineExceeded}, true},
+		{&url.Error{Op: "Get", Err: errors.New("unsupported protocol")}, false},
+		{errors.New("other"), false},
+		{nil, false},
+	}
+
+	for i, test := range transientErrorTests {
+		if actual := isTransientError(test.err); actual != test.expected {
+			t.Errorf("%d. Actual %t for %v doesn't match expected %t", i, actual, test.err, test.expected)
+		}
+	}
+}
@@ -9,6 +9,7 @@ toolchain go1.23.2
 require (
 	fyne.io/fyne/v2 v2.5.2
 	fyne.io/systray v1.11.0
+	github.com/godbus/dbus/v5 v5.1.0
 	github.com/robfig/cron/v3 v3.0.1
 	golang.org/x/oauth2 v0.24.0
 	google.golang.org/api v0.205.0
@@ -32,7 +33,6 @@ require (
 	github.com/go-logr/stdr v1.2.2 // indirect
 	github.com/go-text/render v0.2.0 // indirect
 	github.com/go-text/typesetting v0.2.0 // indirect
-	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/golang/groupcache v0.0.0-20210331224755-41bb18bfe9da // indirect
 	github.com/google/s2a-go v0.1.8 // indirect
 	github.com/google/uuid v1.6.0 // indirect
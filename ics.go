@@ -0,0 +1,561 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Maximum number of instances a recurring event is expanded to in a range, to protect against rules without an end
+const maxIcsInstances = 5000
+
+// Returned for recurrence rules whose instances can't be worked out, like ones on the second Tuesday of the month.
+// Their events are left out instead of being shown on the wrong days
+var errUnsupportedRule = errors.New("unsupported recurrence rule")
+
+var icsClient = &http.Client{Transport: proxiedTransport, Timeout: 30 * time.Second}
+
+var icsDurationRegex = regexp.MustCompile(`^([+-])?P(?:(\d+)W)?(?:(\d+)D)?(?:T(?:(\d+)H)?(?:(\d+)M)?(?:(\d+)S)?)?$`)
+
+// An event source that reads an iCalendar feed from a local file or an https:// or webcal:// URL. The feed is only
+// retrieved again on full refreshes, and only downloaded if the server reports it changed
+type icsEventSource struct {
+	url          string
+	name         string
+	definitions  []icsEvent
+	eventsBuffer []event
+	bufferStart  time.Time
+	bufferEnd    time.Time
+	etag         string
+	lastModified string
+}
+
+// A VEVENT as defined in the feed. Recurring ones are expanded into their instances when retrieving events
+type icsEvent struct {
+	uid          string
+	summary      string
+	description  string
+	location     string
+	url          string
+	start        time.Time
+	duration     time.Duration
+	updated      time.Time
+	cancelled    bool
+	free         bool
+	rule         *recurrenceRule
+	exceptions   []time.Time
+	recurrenceId time.Time
+}
+
+// The supported subset of an RRULE: the frequency with its interval, the end as a count or a date and, for weekly
+// rules, the days of the week
+type recurrenceRule struct {
+	frequency string
+	interval  int
+	count     int
+	until     time.Time
+	weekdays  []time.Weekday
+}
+
+func newIcsEventSource(url string) *icsEventSource {
+	return &icsEventSource{url: url}
+}
+
+func (ics *icsEventSource) getEvents(day time.Time, fullRefresh bool) ([]event, bool, error) {
+	refreshed := false
+	if ics.definitions == nil || fullRefresh {
+		err := ics.retrieve()
+		if err != nil {
+			return nil, false, err
+		}
+		refreshed = true
+	}
+
+	if refreshed || day.Before(ics.bufferStart) || !day.Before(ics.bufferEnd) {
+		ics.expandAround(day)
+	}
+
+	var result []event
+	for _, event := range ics.eventsBuffer {
+		if isOnSameDay(day, event.start) {
+			result = append(result, event)
+		}
+	}
+
+	return result, refreshed, nil
+}
+
+func (ics *icsEventSource) getBufferedEvents() []event {
+	return ics.eventsBuffer
+}
+
+func (ics *icsEventSource) getEventsBetween(start time.Time, end time.Time) ([]event, error) {
+	if ics.definitions == nil {
+		err := ics.retrieve()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return expandIcsEvents(ics.definitions, ics.name, start, end), nil
+}
+
+func (ics *icsEventSource) expandAround(day time.Time) {
+	halfWindow, _ := bufferWindow()
+	ics.bufferStart = startOfDay(day).AddDate(0, 0, -halfWindow)
+	ics.bufferEnd = startOfDay(day).AddDate(0, 0, halfWindow)
+	ics.eventsBuffer = expandIcsEvents(ics.definitions, ics.name, ics.bufferStart, ics.bufferEnd)
+}
+
+// Downloads or reads the feed and parses its events
+func (ics *icsEventSource) retrieve() error {
+	slog.Info("Retrieving iCalendar feed " + ics.url)
+	var reader io.Reader
+	if strings.HasPrefix(ics.url, "https://") || strings.HasPrefix(ics.url, "http://") || strings.HasPrefix(ics.url, "webcal://") {
+		request, err := http.NewRequest(http.MethodGet, strings.Replace(ics.url, "webcal://", "https://", 1), nil)
+		if err != nil {
+			return err
+		}
+		if ics.definitions != nil {
+			if ics.etag != "" {
+				request.Header.Set("If-None-Match", ics.etag)
+			}
+			if ics.lastModified != "" {
+				request.Header.Set("If-Modified-Since", ics.lastModified)
+			}
+		}
+		response, err := icsClient.Do(request)
+		if err != nil {
+			return err
+		}
+		defer response.Body.Close()
+		if response.StatusCode == http.StatusNotModified && ics.definitions != nil {
+			slog.Debug("iCalendar feed not modified")
+			return nil
+		}
+		if response.StatusCode != http.StatusOK {
+			return errors.New(message("ics-retrieve-failed") + response.Status)
+		}
+		ics.etag = response.Header.Get("ETag")
+		ics.lastModified = response.Header.Get("Last-Modified")
+		reader = response.Body
+	} else {
+		file, err := os.Open(ics.url)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	name, definitions, err := parseIcs(reader)
+	if err != nil {
+		return err
+	}
+	slog.Debug("Parsed " + strconv.Itoa(len(definitions)) + " event(s) from the iCalendar feed")
+	ics.name = name
+	ics.definitions = definitions
+
+	return nil
+}
+
+// Parses the events of an iCalendar feed and the name of the calendar. All-day events are left out like in the other
+// sources
+func parseIcs(reader io.Reader) (string, []icsEvent, error) {
+	lines, err := unfoldIcsLines(reader)
+	if err != nil {
+		return "", nil, err
+	}
+
+	name := ""
+	result := []icsEvent{}
+	var current *icsEvent
+	var end time.Time
+	// events whose recurrence rule can't be expanded are left out
+	unsupported := false
+	// components nested in the event, like alarms, whose properties must be ignored
+	nested := 0
+	for _, line := range lines {
+		property, params, value := splitIcsLine(line)
+		switch {
+		case property == "BEGIN" && value == "VEVENT":
+			current = &icsEvent{}
+			end = time.Time{}
+			unsupported = false
+			continue
+		case property == "BEGIN" && current != nil:
+			nested++
+			continue
+		case property == "END" && value == "VEVENT" && current != nil:
+			if !current.start.IsZero() && !unsupported {
+				if !end.IsZero() {
+					current.duration = end.Sub(current.start)
+				}
+				result = append(result, *current)
+			}
+			current = nil
+			continue
+		case property == "END" && current != nil:
+			nested--
+			continue
+		case property == "X-WR-CALNAME":
+			name = unescapeIcsText(value)
+			continue
+		}
+		if current == nil || nested > 0 {
+			continue
+		}
+
+		switch property {
+		case "UID":
+			current.uid = value
+		case "SUMMARY":
+			current.summary = unescapeIcsText(value)
+		case "DESCRIPTION":
+			current.description = unescapeIcsText(value)
+		case "LOCATION":
+			current.location = unescapeIcsText(value)
+		case "URL":
+			current.url = value
+		case "STATUS":
+			current.cancelled = value == "CANCELLED"
+		case "TRANSP":
+			current.free = value == "TRANSPARENT"
+		case "LAST-MODIFIED":
+			current.updated, _ = parseIcsTime(value, params)
+		case "DTSTART":
+			if params["VALUE"] == "DATE" {
+				slog.Debug("Ignoring all-day iCalendar event " + current.uid)
+				continue
+			}
+			current.start, err = parseIcsTime(value, params)
+		case "DTEND":
+			end, err = parseIcsTime(value, params)
+		case "DURATION":
+			current.duration, err = parseIcsDuration(value)
+		case "RRULE":
+			current.rule, err = parseRecurrenceRule(value, params)
+			if errors.Is(err, errUnsupportedRule) {
+				slog.Warn("Leaving out iCalendar event "+current.uid, "error", err)
+				unsupported, err = true, nil
+			}
+		case "EXDATE":
+			for _, exception := range strings.Split(value, ",") {
+				exceptionTime, exceptionErr := parseIcsTime(exception, params)
+				if exceptionErr != nil {
+					err = exceptionErr
+					break
+				}
+				current.exceptions = append(current.exceptions, exceptionTime)
+			}
+		case "RECURRENCE-ID":
+			current.recurrenceId, err = parseIcsTime(value, params)
+		}
+		if err != nil {
+			return "", nil, fmt.Errorf("invalid %s in iCalendar event %s: %w", property, current.uid, err)
+		}
+	}
+
+	return name, result, nil
+}
+
+// Splits the feed in lines, joining the ones that were folded to keep them short
+func unfoldIcsLines(reader io.Reader) ([]string, error) {
+	var result []string
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(result) > 0 {
+			result[len(result)-1] += line[1:]
+		} else if line != "" {
+			result = append(result, line)
+		}
+	}
+
+	return result, scanner.Err()
+}
+
+// Splits a content line like DTSTART;TZID=Europe/Paris:20241118T100000 into its property, parameters and value
+func splitIcsLine(line string) (string, map[string]string, string) {
+	quoted := false
+	separator := -1
+	for pos, char := range line {
+		if char == '"' {
+			quoted = !quoted
+		} else if char == ':' && !quoted {
+			separator = pos
+			break
+		}
+	}
+	if separator < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+
+	parts := strings.Split(line[:separator], ";")
+	params := make(map[string]string)
+	for _, param := range parts[1:] {
+		key, value, _ := strings.Cut(param, "=")
+		params[strings.ToUpper(key)] = strings.Trim(value, `"`)
+	}
+
+	return strings.ToUpper(parts[0]), params, line[separator+1:]
+}
+
+func unescapeIcsText(text string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(text)
+}
+
+// Parses a date-time in UTC (with a Z suffix), in the time zone of the TZID parameter or floating, which is local
+func parseIcsTime(value string, params map[string]string) (time.Time, error) {
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		return time.ParseInLocation("20060102", value, time.Local)
+	}
+	if strings.HasSuffix(value, "Z") {
+		return time.Parse("20060102T150405Z", value)
+	}
+
+	location := time.Local
+	if timeZone := params["TZID"]; timeZone != "" {
+		zoneLocation, err := time.LoadLocation(strings.TrimPrefix(timeZone, "/"))
+		if err == nil {
+			location = zoneLocation
+		} else {
+			slog.Warn("Unknown time zone " + timeZone + ". Using the local one")
+		}
+	}
+
+	return time.ParseInLocation("20060102T150405", value, location)
+}
+
+// Parses a duration like PT1H30M or P1D
+func parseIcsDuration(value string) (time.Duration, error) {
+	match := icsDurationRegex.FindStringSubmatch(value)
+	if match == nil {
+		return 0, errors.New("invalid duration " + value)
+	}
+
+	units := []time.Duration{7 * 24 * time.Hour, 24 * time.Hour, time.Hour, time.Minute, time.Second}
+	var result time.Duration
+	for pos, unit := range units {
+		if amount, err := strconv.Atoi(match[pos+2]); err == nil {
+			result += time.Duration(amount) * unit
+		}
+	}
+	if match[1] == "-" {
+		result = -result
+	}
+
+	return result, nil
+}
+
+func parseRecurrenceRule(value string, params map[string]string) (*recurrenceRule, error) {
+	result := &recurrenceRule{interval: 1}
+	weekdays := map[string]time.Weekday{"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday, "TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday}
+	for _, part := range strings.Split(value, ";") {
+		key, partValue, _ := strings.Cut(part, "=")
+		var err error
+		switch strings.ToUpper(key) {
+		case "FREQ":
+			result.frequency = strings.ToUpper(partValue)
+		case "INTERVAL":
+			result.interval, err = strconv.Atoi(partValue)
+		case "COUNT":
+			result.count, err = strconv.Atoi(partValue)
+		case "UNTIL":
+			result.until, err = parseIcsTime(partValue, params)
+		case "BYDAY":
+			for _, day := range strings.Split(partValue, ",") {
+				weekday, found := weekdays[strings.ToUpper(day)]
+				if !found {
+					return nil, fmt.Errorf("%w: BYDAY=%s", errUnsupportedRule, day)
+				}
+				result.weekdays = append(result.weekdays, weekday)
+			}
+		case "WKST":
+		default:
+			// other BY parts select different instances, which can't be ignored
+			if strings.HasPrefix(strings.ToUpper(key), "BY") {
+				return nil, fmt.Errorf("%w: %s", errUnsupportedRule, part)
+			}
+			slog.Warn("Unsupported " + key + " in recurrence rule. Ignoring it")
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if !slices.Contains([]string{"DAILY", "WEEKLY", "MONTHLY", "YEARLY"}, result.frequency) || result.interval < 1 {
+		return nil, errors.New("unsupported recurrence rule " + value)
+	}
+	if len(result.weekdays) > 0 && result.frequency != "WEEKLY" {
+		return nil, fmt.Errorf("%w: BYDAY in a %s rule", errUnsupportedRule, result.frequency)
+	}
+
+	return result, nil
+}
+
+// Gets the starts of the instances of the event in the range, in order. Rules without a count skip the periods before
+// the range so that long running series still get instances in it
+func (definition *icsEvent) instanceStarts(start time.Time, end time.Time) []time.Time {
+	rule := definition.rule
+	if rule == nil {
+		return []time.Time{definition.start}
+	}
+
+	firstPeriod := 0
+	if rule.count == 0 {
+		// one period earlier in case of daylight saving time changes
+		firstPeriod = max(rule.periodsBetween(definition.start, start)-1, 0)
+	}
+	var result []time.Time
+	// instances before the range still count for the count of the rule
+	instances := 0
+	for period := firstPeriod; len(result) < maxIcsInstances; period++ {
+		var candidates []time.Time
+		switch rule.frequency {
+		case "DAILY":
+			candidates = []time.Time{definition.start.AddDate(0, 0, period*rule.interval)}
+		case "WEEKLY":
+			if len(rule.weekdays) == 0 {
+				candidates = []time.Time{definition.start.AddDate(0, 0, 7*period*rule.interval)}
+				break
+			}
+			// weeks start on Monday
+			weekStart := definition.start.AddDate(0, 0, -(int(definition.start.Weekday())+6)%7+7*period*rule.interval)
+			for _, weekday := range rule.weekdays {
+				candidates = append(candidates, weekStart.AddDate(0, 0, (int(weekday)+6)%7))
+			}
+			slices.SortFunc(candidates, func(one time.Time, other time.Time) int { return one.Compare(other) })
+		case "MONTHLY":
+			candidate := definition.start.AddDate(0, period*rule.interval, 0)
+			// months without the day are skipped
+			if candidate.Day() == definition.start.Day() {
+				candidates = []time.Time{candidate}
+			}
+		case "YEARLY":
+			candidate := definition.start.AddDate(period*rule.interval, 0, 0)
+			if candidate.Day() == definition.start.Day() {
+				candidates = []time.Time{candidate}
+			}
+		}
+
+		for _, candidate := range candidates {
+			if candidate.Before(definition.start) {
+				continue
+			}
+			if !candidate.Before(end) || (!rule.until.IsZero() && candidate.After(rule.until)) ||
+				(rule.count > 0 && instances >= rule.count) {
+				return result
+			}
+			instances++
+			if !candidate.Before(start) {
+				result = append(result, candidate)
+			}
+		}
+	}
+
+	return result
+}
+
+// Gets how many whole periods of the rule there are between the two times
+func (rule *recurrenceRule) periodsBetween(from time.Time, to time.Time) int {
+	if !to.After(from) {
+		return 0
+	}
+
+	var units int
+	switch rule.frequency {
+	case "DAILY":
+		units = int(to.Sub(from).Hours() / 24)
+	case "WEEKLY":
+		units = int(to.Sub(from).Hours() / (7 * 24))
+	case "MONTHLY":
+		units = (to.Year()-from.Year())*12 + int(to.Month()) - int(from.Month())
+	case "YEARLY":
+		units = to.Year() - from.Year()
+	}
+
+	return units / rule.interval
+}
+
+// Converts the event definitions into the events that start in the range, expanding the recurring ones into their
+// instances. Cancelled instances are left out and modified ones replace the originals
+func expandIcsEvents(definitions []icsEvent, source string, start time.Time, end time.Time) []event {
+	// modified instances, by series and original start
+	overrides := make(map[string]*icsEvent)
+	for pos := range definitions {
+		if !definitions[pos].recurrenceId.IsZero() {
+			overrides[definitions[pos].uid+"@"+definitions[pos].recurrenceId.UTC().Format(time.RFC3339)] = &definitions[pos]
+		}
+	}
+
+	var result []event
+	for pos := range definitions {
+		definition := &definitions[pos]
+		if !definition.recurrenceId.IsZero() {
+			continue
+		}
+
+		// modified instances can be moved into the range from before it
+		from := start
+		for _, override := range overrides {
+			if override.uid == definition.uid && override.recurrenceId.Before(from) && !override.start.Before(start) && override.start.Before(end) {
+				from = override.recurrenceId
+			}
+		}
+		for _, instanceStart := range definition.instanceStarts(from, end) {
+			if slices.ContainsFunc(definition.exceptions, instanceStart.Equal) {
+				continue
+			}
+
+			instance := definition
+			id := definition.uid
+			if definition.rule != nil {
+				id += "@" + instanceStart.UTC().Format(time.RFC3339)
+				if override, found := overrides[id]; found {
+					instance = override
+					instanceStart = override.start
+				}
+			}
+			if instance.cancelled || instanceStart.Before(start) || !instanceStart.Before(end) {
+				continue
+			}
+
+			newEvent := event{
+				id:         id,
+				title:      instance.summary,
+				start:      instanceStart,
+				end:        instanceStart.Add(instance.duration),
+				location:   instance.location,
+				details:    instance.description,
+				notifiable: !instance.free,
+				htmlLink:   instance.url,
+				updated:    instance.updated,
+				source:     source,
+				kind:       icsSource,
+				recurring:  definition.rule != nil,
+				free:       instance.free,
+				tags:       extractTags(instance.description),
+				dialIn:     parseDialIn(instance.description),
+			}
+			newEvent.useMeetingLinkInDetails()
+			if newEvent.dialIn.url == "" && newEvent.isVideoMeeting() {
+				newEvent.dialIn.url = newEvent.location
+			}
+			result = append(result, newEvent)
+		}
+	}
+	slices.SortStableFunc(result, func(one event, other event) int { return one.start.Compare(other.start) })
+
+	return result
+}
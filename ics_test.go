@@ -0,0 +1,269 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+const testFeed = `BEGIN:VCALENDAR
+VERSION:2.0
+X-WR-CALNAME:School
+BEGIN:VEVENT
+UID:concert
+SUMMARY:Winter concert\, gym
+DESCRIPTION:Bring a water bottle.\nDoors open 15 min
+ utes earlier #school
+LOCATION:Main gym
+DTSTART;TZID=America/Toronto:20241120T183000
+DTEND;TZID=America/Toronto:20241120T200000
+BEGIN:VALARM
+ACTION:DISPLAY
+DESCRIPTION:Reminder
+TRIGGER:-PT30M
+END:VALARM
+END:VEVENT
+BEGIN:VEVENT
+UID:holiday
+SUMMARY:Holiday
+DTSTART;VALUE=DATE:20241122
+END:VEVENT
+BEGIN:VEVENT
+UID:practice
+SUMMARY:Practice
+DTSTART:20241118T220000Z
+DURATION:PT1H30M
+TRANSP:TRANSPARENT
+RRULE:FREQ=WEEKLY;BYDAY=MO,WE;COUNT=5
+EXDATE:20241120T220000Z
+END:VEVENT
+BEGIN:VEVENT
+UID:practice
+RECURRENCE-ID:20241125T220000Z
+SUMMARY:Practice (moved)
+DTSTART:20241126T220000Z
+DURATION:PT1H
+END:VEVENT
+END:VCALENDAR
+`
+
+func TestParseIcs(t *testing.T) {
+	name, definitions, err := parseIcs(strings.NewReader(strings.ReplaceAll(testFeed, "\n", "\r\n")))
+	if err != nil {
+		t.Fatal("Error parsing feed", err)
+	}
+	if name != "School" {
+		t.Errorf("Actual name %q doesn't match expected School", name)
+	}
+	if len(definitions) != 3 {
+		t.Fatalf("Actual %d events don't match expected 3 without the all-day one", len(definitions))
+	}
+
+	concert := definitions[0]
+	toronto, _ := time.LoadLocation("America/Toronto")
+	if concert.summary != "Winter concert, gym" || concert.location != "Main gym" {
+		t.Errorf("Actual summary %q or location %q were not unescaped", concert.summary, concert.location)
+	}
+	if concert.description != "Bring a water bottle.\nDoors open 15 minutes earlier #school" {
+		t.Errorf("Actual description %q was not unfolded", concert.description)
+	}
+	if !concert.start.Equal(time.Date(2024, 11, 20, 18, 30, 0, 0, toronto)) || concert.duration != 90*time.Minute {
+		t.Errorf("Actual start %v and duration %v don't match expected", concert.start, concert.duration)
+	}
+
+	practice := definitions[1]
+	if practice.rule == nil || practice.rule.count != 5 || !slices.Equal(practice.rule.weekdays, []time.Weekday{time.Monday, time.Wednesday}) {
+		t.Errorf("Actual rule %+v doesn't match expected", practice.rule)
+	}
+	if practice.duration != 90*time.Minute || len(practice.exceptions) != 1 {
+		t.Errorf("Actual duration %v or exceptions %v don't match expected", practice.duration, practice.exceptions)
+	}
+}
+
+func TestExpandIcsEvents(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	_, definitions, err := parseIcs(strings.NewReader(testFeed))
+	if err != nil {
+		t.Fatal("Error parsing feed", err)
+	}
+
+	start := time.Date(2024, 11, 1, 0, 0, 0, 0, time.UTC)
+	actual := expandIcsEvents(definitions, "School", start, start.AddDate(0, 2, 0))
+	var actualStarts []string
+	for _, expanded := range actual {
+		actualStarts = append(actualStarts, expanded.start.UTC().Format("Jan 02 15:04")+" "+expanded.title)
+	}
+	expectedStarts := []string{
+		"Nov 18 22:00 Practice",
+		"Nov 20 23:30 Winter concert, gym",
+		"Nov 26 22:00 Practice (moved)",
+		"Nov 27 22:00 Practice",
+		"Dec 02 22:00 Practice",
+	}
+	if !slices.Equal(actualStarts, expectedStarts) {
+		t.Fatalf("Actual %q don't match expected %q", actualStarts, expectedStarts)
+	}
+	if !actual[0].recurring || actual[1].recurring || actual[1].source != "School" || actual[1].kind != icsSource || !slices.Equal(actual[1].tags, []string{"school"}) {
+		t.Errorf("Actual events %+v don't have the expected fields", actual[:2])
+	}
+	if !actual[0].free || actual[0].notifiable || actual[1].free || !actual[1].notifiable {
+		t.Errorf("Actual events %+v are not free only when transparent", actual[:2])
+	}
+	if actual[2].end.Sub(actual[2].start) != time.Hour {
+		t.Errorf("Actual duration %v of the moved instance doesn't match expected 1h", actual[2].end.Sub(actual[2].start))
+	}
+
+	window := expandIcsEvents(definitions, "School", time.Date(2024, 11, 26, 0, 0, 0, 0, time.UTC), time.Date(2024, 11, 28, 0, 0, 0, 0, time.UTC))
+	if len(window) != 2 {
+		t.Errorf("Actual %d events in the window don't match expected 2", len(window))
+	}
+}
+
+type instanceStartsTest struct {
+	rule     string
+	start    time.Time
+	expected []time.Time
+}
+
+func TestInstanceStarts(t *testing.T) {
+	at := func(month time.Month, day int) time.Time {
+		return time.Date(2024, month, day, 9, 0, 0, 0, time.UTC)
+	}
+	var instanceStartsTests = []instanceStartsTest{
+		{"FREQ=DAILY;COUNT=3", at(1, 30), []time.Time{at(1, 30), at(1, 31), at(2, 1)}},
+		{"FREQ=DAILY;INTERVAL=2;UNTIL=20240205T090000Z", at(2, 1), []time.Time{at(2, 1), at(2, 3), at(2, 5)}},
+		{"FREQ=WEEKLY;BYDAY=TU,FR;COUNT=4", at(1, 5), []time.Time{at(1, 5), at(1, 9), at(1, 12), at(1, 16)}},
+		{"FREQ=WEEKLY;INTERVAL=2;COUNT=2", at(1, 1), []time.Time{at(1, 1), at(1, 15)}},
+		{"FREQ=MONTHLY;COUNT=3", at(1, 31), []time.Time{at(1, 31), at(3, 31), at(5, 31)}},
+		{"FREQ=YEARLY;COUNT=2", at(3, 1), []time.Time{at(3, 1), time.Date(2025, 3, 1, 9, 0, 0, 0, time.UTC)}},
+		{"FREQ=DAILY", time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC), []time.Time{time.Date(2025, 12, 30, 9, 0, 0, 0, time.UTC), time.Date(2025, 12, 31, 9, 0, 0, 0, time.UTC)}},
+	}
+
+	for i, test := range instanceStartsTests {
+		rule, err := parseRecurrenceRule(test.rule, nil)
+		if err != nil {
+			t.Fatalf("%d. Error parsing rule %v", i, err)
+		}
+		definition := icsEvent{start: test.start, rule: rule}
+		actual := definition.instanceStarts(time.Time{}, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC))
+		if !slices.EqualFunc(actual, test.expected, time.Time.Equal) {
+			t.Errorf("%d. Actual %v don't match expected %v", i, actual, test.expected)
+		}
+	}
+}
+
+func TestInstanceStartsOfLongRunningSeries(t *testing.T) {
+	rule, err := parseRecurrenceRule("FREQ=DAILY", nil)
+	if err != nil {
+		t.Fatal("Error parsing rule", err)
+	}
+	definition := icsEvent{start: time.Date(2005, 1, 3, 9, 0, 0, 0, time.UTC), rule: rule}
+
+	start := time.Date(2024, 11, 18, 0, 0, 0, 0, time.UTC)
+	actual := definition.instanceStarts(start, start.AddDate(0, 0, 2))
+	expected := []time.Time{start.Add(9 * time.Hour), start.AddDate(0, 0, 1).Add(9 * time.Hour)}
+	if !slices.EqualFunc(actual, expected, time.Time.Equal) {
+		t.Errorf("Actual %v don't match expected %v", actual, expected)
+	}
+}
+
+func TestParseIcsLeavesOutUnsupportedRules(t *testing.T) {
+	feed := `BEGIN:VCALENDAR
+BEGIN:VEVENT
+UID:planning
+DTSTART:20241112T150000Z
+RRULE:FREQ=MONTHLY;BYDAY=2TU
+END:VEVENT
+BEGIN:VEVENT
+UID:retro
+DTSTART:20241101T150000Z
+RRULE:FREQ=MONTHLY;BYDAY=FR
+END:VEVENT
+BEGIN:VEVENT
+UID:review
+DTSTART:20241115T150000Z
+RRULE:FREQ=MONTHLY;BYMONTHDAY=15,30
+END:VEVENT
+BEGIN:VEVENT
+UID:standup
+DTSTART:20241118T150000Z
+RRULE:FREQ=WEEKLY;BYDAY=MO
+END:VEVENT
+END:VCALENDAR
+`
+	_, definitions, err := parseIcs(strings.NewReader(feed))
+	if err != nil {
+		t.Fatal("Error parsing feed", err)
+	}
+	if len(definitions) != 1 || definitions[0].uid != "standup" {
+		t.Errorf("Actual definitions %+v don't match the expected supported one", definitions)
+	}
+}
+
+func TestIcsEventSourceCachesFeed(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	path := filepath.Join(t.TempDir(), "school.ics")
+	err := os.WriteFile(path, []byte(testFeed), 0600)
+	if err != nil {
+		t.Fatal("Error writing feed", err)
+	}
+
+	source := newIcsEventSource(path)
+	day := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+	events, refreshed, err := source.getEvents(day, false)
+	if err != nil || !refreshed || len(events) == 0 {
+		t.Fatalf("Actual events %v, refreshed = %t, error %v don't match expected", events, refreshed, err)
+	}
+
+	_ = os.WriteFile(path, []byte("BEGIN:VCALENDAR\nEND:VCALENDAR\n"), 0600)
+	if _, refreshed, _ := source.getEvents(day, false); refreshed || len(source.getBufferedEvents()) == 0 {
+		t.Errorf("Feed was retrieved again without a full refresh")
+	}
+	if _, refreshed, _ := source.getEvents(day, true); !refreshed || len(source.getBufferedEvents()) != 0 {
+		t.Errorf("Feed was not retrieved again on a full refresh")
+	}
+}
+
+func TestIcsEventSourceUsesHttpCaching(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var conditionalRequests int
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("If-None-Match") == `"v1"` && request.Header.Get("If-Modified-Since") == "Mon, 18 Nov 2024 10:00:00 GMT" {
+			conditionalRequests++
+			writer.WriteHeader(http.StatusNotModified)
+			return
+		}
+		writer.Header().Set("ETag", `"v1"`)
+		writer.Header().Set("Last-Modified", "Mon, 18 Nov 2024 10:00:00 GMT")
+		_, _ = writer.Write([]byte(testFeed))
+	}))
+	defer server.Close()
+
+	source := newIcsEventSource(server.URL + "/school.ics")
+	day := time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+	events, _, err := source.getEvents(day, false)
+	if err != nil || len(events) != 1 {
+		t.Fatalf("Actual events %v or error %v don't match expected", events, err)
+	}
+
+	events, refreshed, err := source.getEvents(day, true)
+	if err != nil || !refreshed || len(events) != 1 {
+		t.Errorf("Actual events %v, refreshed = %t or error %v don't match expected after not modified", events, refreshed, err)
+	}
+	if conditionalRequests != 1 {
+		t.Errorf("Actual %d conditional requests don't match expected 1", conditionalRequests)
+	}
+}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const showCommand = "show"
+
+var instanceListener net.Listener
+
+// Makes this the only running instance of the app by listening on a socket in the app folder. If another instance is
+// already listening, it is asked to show its window and false is returned so that this one exits
+func listenForInstances() bool {
+	folder := dailyApp.Storage().RootURI().Path()
+	err := os.MkdirAll(folder, 0700)
+	if err != nil {
+		slog.Warn("Could not create app folder. Not enforcing a single instance", "error", err)
+		return true
+	}
+
+	socketPath := filepath.Join(folder, "instance.sock")
+	listener, err := net.Listen("unix", socketPath)
+	if err != nil {
+		connection, dialErr := net.Dial("unix", socketPath)
+		if dialErr == nil {
+			defer connection.Close()
+			fmt.Fprintln(connection, showCommand)
+			return false
+		}
+
+		slog.Debug("Removing socket left behind by an instance that didn't stop cleanly")
+		os.Remove(socketPath)
+		listener, err = net.Listen("unix", socketPath)
+		if err != nil {
+			slog.Warn("Could not listen for other instances. Not enforcing a single instance", "error", err)
+			return true
+		}
+	}
+
+	instanceListener = listener
+	go acceptInstances(listener)
+	return true
+}
+
+func acceptInstances(listener net.Listener) {
+	for {
+		connection, err := listener.Accept()
+		if err != nil {
+			slog.Debug("Stopped listening for other instances", "error", err)
+			return
+		}
+
+		command, _ := bufio.NewReader(connection).ReadString('\n')
+		connection.Close()
+		if strings.TrimSpace(command) == showCommand {
+			slog.Info("Another instance was started. Showing the main window")
+			showMainWindow()
+		}
+	}
+}
+
+func stopListeningForInstances() {
+	if instanceListener != nil {
+		instanceListener.Close()
+	}
+}
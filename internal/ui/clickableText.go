@@ -15,11 +15,15 @@ type ClickableText struct {
 	widget.BaseWidget
 
 	text          *canvas.Text
+	fullText      string
 	background    *canvas.Rectangle
 	rootContainer *fyne.Container
 	tapAnim       *fyne.Animation
 
-	OnTapped func(*fyne.PointEvent)
+	OnTapped          func(*fyne.PointEvent)
+	OnTappedSecondary func(*fyne.PointEvent)
+	// Whether to shorten the text with an ellipsis when it doesn't fit, instead of growing
+	Truncate bool
 }
 
 func NewClickableText(text string, style fyne.TextStyle, colour color.Color) *ClickableText {
@@ -34,6 +38,7 @@ func NewClickableText(text string, style fyne.TextStyle, colour color.Color) *Cl
 			TextStyle: style,
 			Color:     colour,
 		},
+		fullText:   text,
 		background: canvas.NewRectangle(color.Transparent),
 	}
 	result.ExtendBaseWidget(result)
@@ -44,6 +49,16 @@ func NewClickableText(text string, style fyne.TextStyle, colour color.Color) *Cl
 	return result
 }
 
+// Update changes the text and how it looks
+func (clickable *ClickableText) Update(text string, style fyne.TextStyle, colour color.Color) {
+	clickable.fullText = text
+	clickable.text.Text = text
+	clickable.text.TextStyle = style
+	clickable.text.Color = colour
+	clickable.text.Refresh()
+	clickable.Refresh()
+}
+
 func (clickable *ClickableText) Tapped(event *fyne.PointEvent) {
 	clickable.tapAnimation()
 	if clickable.OnTapped != nil {
@@ -51,8 +66,72 @@ func (clickable *ClickableText) Tapped(event *fyne.PointEvent) {
 	}
 }
 
+// TappedSecondary is called on right-click on desktop or long-press on mobile
+func (clickable *ClickableText) TappedSecondary(event *fyne.PointEvent) {
+	if clickable.OnTappedSecondary != nil {
+		clickable.tapAnimation()
+		clickable.OnTappedSecondary(event)
+	}
+}
+
 func (clickable *ClickableText) CreateRenderer() fyne.WidgetRenderer {
-	return widget.NewSimpleRenderer(clickable.rootContainer)
+	return &clickableTextRenderer{clickable: clickable}
+}
+
+// fitText shortens the text, if needed, so that it fits in the width
+func (clickable *ClickableText) fitText(width float32) {
+	clickable.text.Text = clickable.fullText
+	if !clickable.Truncate || clickable.measure(clickable.fullText) <= width {
+		return
+	}
+
+	runes := []rune(clickable.fullText)
+	for length := len(runes) - 1; length > 0; length-- {
+		candidate := string(runes[:length]) + ellipsis
+		if clickable.measure(candidate) <= width {
+			clickable.text.Text = candidate
+			return
+		}
+	}
+	clickable.text.Text = ellipsis
+}
+
+func (clickable *ClickableText) measure(text string) float32 {
+	return fyne.MeasureText(text, clickable.text.TextSize, clickable.text.TextStyle).Width
+}
+
+const ellipsis = "…"
+
+type clickableTextRenderer struct {
+	clickable *ClickableText
+}
+
+func (renderer *clickableTextRenderer) Layout(size fyne.Size) {
+	renderer.clickable.fitText(size.Width)
+	renderer.clickable.rootContainer.Resize(size)
+}
+
+func (renderer *clickableTextRenderer) MinSize() fyne.Size {
+	renderer.clickable.text.Text = renderer.clickable.fullText
+	result := renderer.clickable.rootContainer.MinSize()
+	if renderer.clickable.Truncate {
+		result.Width = renderer.clickable.measure(ellipsis)
+	}
+	renderer.clickable.fitText(renderer.clickable.Size().Width)
+
+	return result
+}
+
+func (renderer *clickableTextRenderer) Refresh() {
+	renderer.Layout(renderer.clickable.Size())
+	renderer.clickable.text.Refresh()
+}
+
+func (renderer *clickableTextRenderer) Objects() []fyne.CanvasObject {
+	return []fyne.CanvasObject{renderer.clickable.rootContainer}
+}
+
+func (renderer *clickableTextRenderer) Destroy() {
 }
 
 func (clickable *ClickableText) MouseIn(*desktop.MouseEvent) {
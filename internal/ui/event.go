@@ -2,61 +2,236 @@ package ui
 
 import (
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
 )
 
 type Event struct {
 	widget.BaseWidget
 
+	Icon         *widget.Icon
 	Title        *ClickableText
 	TitleButtons []*widget.Button
 	Detail       fyne.CanvasObject
+	OnOpened     func()
+	detailArea   *fyne.Container
 	open         bool
+	badge        *widget.Icon
+	marker       *widget.Icon
+	subtitle     *canvas.Text
+	progress     *fyne.Container
+	tags         *fyne.Container
+	titleBox     *fyne.Container
 	container    *fyne.Container
 }
 
-func NewEvent(icon *widget.Icon,title *ClickableText, titleButtons []*widget.Button, detail fyne.CanvasObject) *Event {
-	titleBox := container.NewHBox(icon, title, layout.NewSpacer())
-	for _, button := range titleButtons {
-		titleBox.Add(button)
-	}
+func NewEvent(icon *widget.Icon, title *ClickableText, titleButtons []*widget.Button, detail fyne.CanvasObject) *Event {
+	titleBox := container.NewWithoutLayout()
+	subtitle := canvas.NewText("", theme.PlaceHolderColor())
+	subtitle.TextSize = theme.CaptionTextSize()
+	subtitle.Hide()
+
+	progressBar := canvas.NewRectangle(theme.PrimaryColor())
+	progress := container.New(&progressLayout{}, progressBar)
+	progress.Hide()
+
+	tags := container.NewHBox()
+	tags.Hide()
 
-	detail.Hide()
-	rootContainer := container.NewVBox(container.NewPadded(titleBox), detail, widget.NewSeparator())
+	detailArea := container.New(&maxHeightLayout{}, container.NewVScroll(detail))
+	detailArea.Hide()
+	rootContainer := container.NewVBox(container.NewPadded(container.NewVBox(titleBox, subtitle, tags, progress)), detailArea, widget.NewSeparator())
+	badge := widget.NewIcon(nil)
+	badge.Hide()
+	marker := widget.NewIcon(nil)
+	marker.Hide()
 	result := &Event{
-		Title:        title,
-		TitleButtons: titleButtons,
-		Detail:       detail,
-		open:         false,
-		container:    rootContainer,
+		Icon:       icon,
+		Title:      title,
+		Detail:     detail,
+		detailArea: detailArea,
+		open:       false,
+		badge:      badge,
+		marker:     marker,
+		subtitle:   subtitle,
+		progress:   progress,
+		tags:       tags,
+		titleBox:   titleBox,
+		container:  rootContainer,
 	}
 	result.ExtendBaseWidget(result)
+	result.SetTitleButtons(titleButtons)
 
 	title.OnTapped = func(pe *fyne.PointEvent) {
-		if result.open {
-			result.Close()
-		} else {
-			result.Open()
-		}
+		result.Toggle()
 	}
 
 	return result
 }
 
+// SetTitleButtons replaces the buttons shown next to the title
+func (event *Event) SetTitleButtons(titleButtons []*widget.Button) {
+	event.TitleButtons = titleButtons
+	buttonsBox := container.NewHBox()
+	for _, button := range titleButtons {
+		buttonsBox.Add(button)
+	}
+	// the title takes all the space between the icons and the buttons so that it can be truncated if needed
+	event.titleBox.Objects = []fyne.CanvasObject{event.Title, container.NewHBox(event.Icon, event.marker, event.badge), buttonsBox}
+	event.titleBox.Layout = layout.NewBorderLayout(nil, nil, event.titleBox.Objects[1], buttonsBox)
+	event.titleBox.Refresh()
+}
+
+// ShowBadge displays a small icon next to the title to draw attention to the event
+func (event *Event) ShowBadge(resource fyne.Resource) {
+	event.badge.SetResource(resource)
+	event.badge.Show()
+}
+
+func (event *Event) HideBadge() {
+	event.badge.Hide()
+}
+
+// SetMarker shows a small icon next to the title to distinguish the event from others. A nil resource hides it
+func (event *Event) SetMarker(resource fyne.Resource) {
+	event.marker.SetResource(resource)
+	if resource == nil {
+		event.marker.Hide()
+	} else {
+		event.marker.Show()
+	}
+}
+
+// SetSubtitle shows a small line of text under the title. An empty text hides it
+func (event *Event) SetSubtitle(text string) {
+	event.subtitle.Text = text
+	if text == "" {
+		event.subtitle.Hide()
+	} else {
+		event.subtitle.Show()
+	}
+	event.subtitle.Refresh()
+}
+
+// SetTags shows the tags as small chips under the title. No tags hides them
+func (event *Event) SetTags(tags []string) {
+	event.tags.RemoveAll()
+	for _, tag := range tags {
+		event.tags.Add(newChip("#" + tag))
+	}
+	if len(tags) == 0 {
+		event.tags.Hide()
+	} else {
+		event.tags.Show()
+	}
+}
+
+func newChip(text string) fyne.CanvasObject {
+	label := canvas.NewText(text, theme.ForegroundColor())
+	label.TextSize = theme.CaptionTextSize()
+	background := canvas.NewRectangle(theme.InputBackgroundColor())
+	background.CornerRadius = theme.InputRadiusSize()
+	padding := theme.Padding()
+
+	return container.NewStack(background, container.New(layout.NewCustomPaddedLayout(0, 0, padding, padding), label))
+}
+
+// SetProgress shows a thin bar under the title filled to the given fraction (between 0 and 1). A negative fraction
+// hides it
+func (event *Event) SetProgress(fraction float64) {
+	if fraction < 0 {
+		event.progress.Hide()
+		return
+	}
+
+	event.progress.Layout.(*progressLayout).fraction = min(fraction, 1)
+	event.progress.Show()
+	event.progress.Refresh()
+}
+
+// SetDetailMaxHeight limits the height of the detail, which scrolls when it is taller. Zero or less removes the limit
+func (event *Event) SetDetailMaxHeight(height float32) {
+	event.detailArea.Layout.(*maxHeightLayout).maxHeight = height
+	event.detailArea.Refresh()
+}
+
 func (event *Event) Close() {
 	event.open = false
-	event.Detail.Hide()
+	event.detailArea.Hide()
 	event.Refresh()
 }
 
 func (event *Event) Open() {
 	event.open = true
-	event.Detail.Show()
+	event.detailArea.Show()
 	event.Refresh()
+	if event.OnOpened != nil {
+		event.OnOpened()
+	}
+}
+
+// IsOpen returns whether the detail is shown
+func (event *Event) IsOpen() bool {
+	return event.open
+}
+
+func (event *Event) Toggle() {
+	if event.open {
+		event.Close()
+	} else {
+		event.Open()
+	}
 }
 
 func (event *Event) CreateRenderer() fyne.WidgetRenderer {
 	return widget.NewSimpleRenderer(event.container)
 }
+
+// Lays out a single object filling a fraction of the available width
+type progressLayout struct {
+	fraction float64
+}
+
+func (progress *progressLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	for _, object := range objects {
+		object.Move(fyne.NewPos(0, 0))
+		object.Resize(fyne.NewSize(size.Width*float32(progress.fraction), size.Height))
+	}
+}
+
+func (progress *progressLayout) MinSize([]fyne.CanvasObject) fyne.Size {
+	return fyne.NewSize(0, theme.Padding()/2)
+}
+
+// Lays out objects filling all the available space, but asks for no more height than the maximum, if there is one. The
+// objects are expected to be scroll containers so that their content is still reachable
+type maxHeightLayout struct {
+	maxHeight float32
+}
+
+func (maxHeight *maxHeightLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	for _, object := range objects {
+		object.Move(fyne.NewPos(0, 0))
+		object.Resize(size)
+	}
+}
+
+func (maxHeight *maxHeightLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	result := fyne.NewSize(0, 0)
+	for _, object := range objects {
+		objectSize := object.MinSize()
+		if scroll, ok := object.(*container.Scroll); ok {
+			// scrolls only ask for a small height so the content's is used
+			objectSize.Height = scroll.Content.MinSize().Height
+		}
+		result = result.Max(objectSize)
+	}
+	if maxHeight.maxHeight > 0 {
+		result.Height = min(result.Height, maxHeight.maxHeight)
+	}
+
+	return result
+}
@@ -0,0 +1,88 @@
+package ui
+
+import (
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+)
+
+// overflowLayout arranges objects horizontally. When there isn't enough width for all of them, the collapsible objects
+// are hidden and the overflow object is shown in their place
+type overflowLayout struct {
+	overflow    fyne.CanvasObject
+	collapsible []fyne.CanvasObject
+}
+
+// NewOverflowLayout creates a horizontal layout that replaces the collapsible objects with the overflow one (e.g. a
+// button that opens a menu) when the container is too narrow. Both the overflow and the collapsible objects must also be
+// objects of the container
+func NewOverflowLayout(overflow fyne.CanvasObject, collapsible ...fyne.CanvasObject) fyne.Layout {
+	return &overflowLayout{overflow: overflow, collapsible: collapsible}
+}
+
+func (overflow *overflowLayout) Layout(objects []fyne.CanvasObject, size fyne.Size) {
+	fits := size.Width >= overflow.width(objects, true)
+	for _, object := range overflow.collapsible {
+		setVisible(object, fits)
+	}
+	setVisible(overflow.overflow, !fits)
+
+	layout.NewHBoxLayout().Layout(objects, size)
+}
+
+func (overflow *overflowLayout) MinSize(objects []fyne.CanvasObject) fyne.Size {
+	height := float32(0)
+	for _, object := range objects {
+		height = max(height, object.MinSize().Height)
+	}
+
+	return fyne.NewSize(overflow.width(objects, false), height)
+}
+
+// Gets the width needed for the objects, either expanded with all the collapsible ones or collapsed with the overflow
+// one
+func (overflow *overflowLayout) width(objects []fyne.CanvasObject, expanded bool) float32 {
+	result := float32(0)
+	count := 0
+	for _, object := range objects {
+		if object == overflow.overflow {
+			if expanded {
+				continue
+			}
+		} else if overflow.isCollapsible(object) {
+			if !expanded {
+				continue
+			}
+		} else if !object.Visible() {
+			continue
+		}
+
+		if spacer, ok := object.(layout.SpacerObject); !ok || !spacer.ExpandHorizontal() {
+			result += object.MinSize().Width
+		}
+		count++
+	}
+	if count > 1 {
+		result += theme.Padding() * float32(count-1)
+	}
+
+	return result
+}
+
+func (overflow *overflowLayout) isCollapsible(object fyne.CanvasObject) bool {
+	for _, collapsible := range overflow.collapsible {
+		if object == collapsible {
+			return true
+		}
+	}
+
+	return false
+}
+
+func setVisible(object fyne.CanvasObject, visible bool) {
+	if visible && !object.Visible() {
+		object.Show()
+	} else if !visible && object.Visible() {
+		object.Hide()
+	}
+}
@@ -0,0 +1,110 @@
+package main
+
+// User-facing texts keyed by language and then by message key. English is used for anything missing in a language
+var messages = map[string]map[string]string{
+	"en": {
+		"show":                  "Show",
+		"snooze-notifications":  "Snooze notifications",
+		"snooze-15-minutes":     "15 minutes",
+		"snooze-30-minutes":     "30 minutes",
+		"snooze-1-hour":         "1 hour",
+		"snooze-until-tomorrow": "Until tomorrow",
+		"resume-notifications":  "Resume notifications",
+		"snoozed-until":         "Notifications snoozed until ",
+		"quit":                  "Quit",
+		"no-events":             "No events today",
+		"retrieve-error":        "Could not retrieve calendar events:",
+		"offline":               "You appear to be offline",
+		"access-expired":        "Access to Google Calendar expired or was revoked. Please reconnect in the Settings",
+		"rate-limited":          "Google Calendar is rate limiting requests. Will retry shortly",
+		"access-denied":         "Access to the calendar was denied. Please reconnect in the Settings",
+		"reconnect":             "Reconnect to Google Calendar",
+		"reconnect-scopes":      "Daily needs more permissions for Google Calendar than it was granted. Please reconnect in the Settings",
+		"timeout":               "Google Calendar did not respond in time",
+		"invalid-blocklist":     "Some title-blocklist patterns are invalid and were ignored:",
+		"no-browser":            "Could not open browser",
+		"link-copied":           "The link was copied to the clipboard",
+		"settings":              "Settings",
+		"connect-to":            "Connect to",
+		"connect-manually":      "Manually",
+		"calendar-id":           "Calendar ID:",
+		"save":                  "Save",
+		"validating-calendar":   "Checking the calendar ID",
+		"open-config-folder":    "Open config folder",
+		"search":                "Search",
+		"search-placeholder":    "Title or details",
+		"no-matches":            "No matching events",
+		"close":                 "Close",
+		"copy-dial-in":          "Copy dial-in info",
+		"all-tags":              "All tags",
+		"weekly-summary":        "Your week ahead",
+		"meetings-summary":      "%d meeting(s) taking %s",
+		"morning-briefing":      "Your day",
+		"first-meeting":         "First meeting: ",
+		"first-free-slot":       "First free slot: ",
+		"accent-color":          "Accent color",
+		"default-color":         "Default",
+		"status-busy":           "Busy: ",
+		"status-next":           "Next: ",
+		"status-free":           "Free",
+		"shown-as-free":         "Shown as free",
+		"slack-status":          "In a meeting",
+		"slack-enabled":         "Set the Slack status during meetings",
+		"slack-token":           "Slack token:",
+		"slack-token-missing":   "Enter a Slack token first",
+		"slack-connected":       "Connected. Current status: %s",
+		"slack-no-status":       "none",
+		"slack-missing-scope":   "The Slack token can't check if you set yourself away. Add the users:read and dnd:read scopes to the Slack app and install it again:",
+		"test-connection":       "Test connection",
+		"until":                 "until ",
+		"ics-url":               "iCalendar feed:",
+		"ics-url-placeholder":   "File, https:// or webcal:// URL",
+		"events-changed":        "%d event(s) changed",
+		"change-added":          "Added: ",
+		"change-removed":        "Removed: ",
+		"change-moved":          "Moved: ",
+		"week-of":               "Week of ",
+		"today":                 "Today",
+		"respond-accepted":      "Yes",
+		"respond-tentative":     "Maybe",
+		"respond-declined":      "No",
+		"response-failed":       "Could not send the response to the invitation:",
+		"reconnect-to-respond":  "Daily is not allowed to change your events. Please reconnect in the Settings to respond to invitations",
+		"proxy-url":             "Proxy:",
+		"proxy-url-placeholder": "host:port. HTTPS_PROXY is used if empty",
+		"join-meeting":          "Join",
+		"show-event":            "Show",
+		"snooze-reminder":       "Snooze %d min",
+		"starting-soon":         "'%s' is starting soon",
+		"starting-now":          "'%s' is starting now",
+		"minutes-to-event":      "%d minutes to event",
+		"minute-to-event":       "%d minute to event",
+		"copy":                  "Copy",
+		"submit":                "Submit",
+		"open-url-in-browser":   "Open this URL in a browser:",
+		"paste-result":          "If the browser is on another machine, paste the result here:",
+		"redirect-placeholder":  "Code or URL you were redirected to",
+		"open-url-manually":     "Open URL manually",
+		"no-auth-code":          "No authorization code entered",
+		"invalid-auth-state":    "Invalid state in the pasted url",
+		"auth-invalid-state":    "Invalid state",
+		"auth-failed":           "Failed to exchange token",
+		"auth-complete":         "Authentication Complete",
+		"calendar-not-found":    "Calendar not found or no access: ",
+		"valid-calendar-ids":    "Valid calendar IDs are:",
+		"not-an-attendee":       "Not an attendee of event ",
+		"event-unavailable":     "Event %s is no longer available",
+		"ics-retrieve-failed":   "Could not retrieve iCalendar feed: ",
+		"slack-test-title":      "Slack",
+	},
+}
+
+// Gets the text of a message in the language set in the preferences
+func message(key string) string {
+	language := dailyApp.Preferences().StringWithFallback("language", "en")
+	if text, found := messages[language][key]; found {
+		return text
+	}
+
+	return messages["en"][key]
+}
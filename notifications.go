@@ -0,0 +1,118 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"fyne.io/fyne/v2"
+)
+
+// Ways of showing notifications other than Fyne's, keyed by the name used in the notification-backend preference.
+// Platform specific backends register themselves if they are viable. Backends that support actions offer the ones
+// given with the notification
+var notificationBackends = map[string]func(notification *fyne.Notification, actions notificationActions) error{}
+
+// Whether the user turned on do not disturb in the OS, in which case reminders are not notified. Platforms that can
+// tell replace it
+var isDoNotDisturb = func() bool { return false }
+
+// Backend used when the notification-backend preference is not set. Platforms with a better one than Fyne's change it
+var defaultNotificationBackend = "fyne"
+
+// Guards the notified reminders, which are checked by the cron job and by snoozed reminders
+var notifiedMutex sync.Mutex
+
+const (
+	// Sound of the notification server, used when the notification-sound preference is not set
+	defaultSound = "default"
+	// Shows notifications without any sound
+	silentSound = "silent"
+)
+
+// What the user can do from the notification of an event
+type notificationActions struct {
+	// Link to join the meeting, if it's a virtual one
+	meetingLink string
+	// Notifies again in a few minutes. Nil if the notification can't be snoozed
+	snooze func()
+	// Shows the event in the main window when the notification is clicked. Nil if it isn't about an event
+	show func()
+}
+
+// Sends the notification with the backend set in the preferences, falling back to Fyne's when it is unknown or fails.
+// Fyne's notifications can't have actions so the meeting link is added to the content instead
+func sendNotification(notification *fyne.Notification, actions notificationActions) {
+	name := dailyApp.Preferences().StringWithFallback("notification-backend", defaultNotificationBackend)
+	if backend, found := notificationBackends[name]; found {
+		err := backend(notification, actions)
+		if err == nil {
+			return
+		}
+		slog.Warn("Could not send notification with "+name+". Using the default backend", "error", err)
+	} else if name != "fyne" {
+		slog.Warn("Unknown notification-backend " + name + ". Using the default backend")
+	}
+
+	dailyApp.SendNotification(withMeetingLink(notification, actions.meetingLink))
+}
+
+// Gets the sound to play with notifications from the notification-sound preference: defaultSound, silentSound or the
+// name of a sound of the platform. Fyne's notifications always use the default one
+func notificationSound() string {
+	sound := strings.TrimSpace(dailyApp.Preferences().String("notification-sound"))
+	if sound == "" || strings.EqualFold(sound, defaultSound) {
+		return defaultSound
+	}
+	if strings.EqualFold(sound, silentSound) {
+		return silentSound
+	}
+
+	return sound
+}
+
+// Gets a copy of the notification with the meeting link in a line of its own at the end of the content
+func withMeetingLink(notification *fyne.Notification, meetingLink string) *fyne.Notification {
+	if meetingLink == "" {
+		return notification
+	}
+
+	return fyne.NewNotification(notification.Title, notification.Content+"\n"+meetingLink)
+}
+
+// Whether the reminder with the key was already notified today
+func isNotified(notificationKey string) bool {
+	notifiedMutex.Lock()
+	defer notifiedMutex.Unlock()
+	preferences := dailyApp.Preferences()
+	return preferences.String("notified-date") == now().Format(time.DateOnly) && slices.Contains(preferences.StringList("notified-events"), notificationKey)
+}
+
+// Records that the reminder with the key was notified. They are kept in preferences so that restarting the app doesn't
+// notify them again
+func markNotified(notificationKey string) {
+	notifiedMutex.Lock()
+	defer notifiedMutex.Unlock()
+	forgetOldNotified()
+	preferences := dailyApp.Preferences()
+	preferences.SetString("notified-date", now().Format(time.DateOnly))
+	preferences.SetStringList("notified-events", append(preferences.StringList("notified-events"), notificationKey))
+}
+
+// Forgets the reminders notified on previous days
+func clearOldNotified() {
+	notifiedMutex.Lock()
+	defer notifiedMutex.Unlock()
+	forgetOldNotified()
+}
+
+func forgetOldNotified() {
+	preferences := dailyApp.Preferences()
+	if date := preferences.String("notified-date"); date != "" && date != now().Format(time.DateOnly) {
+		slog.Debug("Forgetting the reminders notified on " + date)
+		preferences.RemoveValue("notified-date")
+		preferences.RemoveValue("notified-events")
+	}
+}
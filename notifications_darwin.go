@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"fyne.io/fyne/v2"
+)
+
+func init() {
+	isDoNotDisturb = focusActive
+	// Fyne's notifications can't have actions, so terminal-notifier is preferred when it is installed
+	if _, err := exec.LookPath("terminal-notifier"); err == nil {
+		defaultNotificationBackend = "terminal-notifier"
+		notificationBackends["terminal-notifier"] = terminalNotifier
+	}
+	if _, err := exec.LookPath("osascript"); err == nil {
+		notificationBackends["osascript"] = osascriptNotification
+	}
+}
+
+// Sends the notification with terminal-notifier. Clicking the notification of a meeting joins it, and reminders have a
+// button to snooze them. With actions, terminal-notifier waits until the notification is dismissed and prints the
+// action picked, so that is handled in the background. Versions without actions show the notification without them
+func terminalNotifier(notification *fyne.Notification, actions notificationActions) error {
+	args := []string{"-title", "Daily", "-subtitle", notification.Title, "-message", notification.Content, "-group", "daily"}
+	if sound := notificationSound(); sound != silentSound {
+		args = append(args, "-sound", sound)
+	}
+	if actions.meetingLink != "" {
+		args = append(args, "-open", actions.meetingLink)
+	}
+	if actions.snooze == nil {
+		return exec.Command("terminal-notifier", args...).Run()
+	}
+
+	snoozeLabel := fmt.Sprintf(message("snooze-reminder"), snoozeReminderMinutes())
+	var output bytes.Buffer
+	command := exec.Command("terminal-notifier", append(args, "-actions", snoozeLabel)...)
+	command.Stdout = &output
+	err := command.Start()
+	if err != nil {
+		return err
+	}
+	go func() {
+		err := command.Wait()
+		if err != nil {
+			slog.Warn("terminal-notifier failed", "error", err)
+			return
+		}
+		if strings.TrimSpace(output.String()) == snoozeLabel {
+			slog.Info("Snoozing reminder from notification")
+			actions.snooze()
+		}
+	}()
+
+	return nil
+}
+
+// Sends the notification with AppleScript, which is always available but can't have actions
+func osascriptNotification(notification *fyne.Notification, actions notificationActions) error {
+	notification = withMeetingLink(notification, actions.meetingLink)
+	script := "display notification " + strconv.Quote(notification.Content) + " with title " + strconv.Quote(notification.Title)
+	if sound := notificationSound(); sound != silentSound && sound != defaultSound {
+		script += " sound name " + strconv.Quote(sound)
+	}
+
+	return exec.Command("osascript", "-e", script).Run()
+}
+
+// Whether a Focus like Do Not Disturb was turned on manually. macOS has no API for it, so the assertions it keeps in
+// the user's library are checked instead
+func focusActive() bool {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return false
+	}
+	content, err := os.ReadFile(filepath.Join(home, "Library", "DoNotDisturb", "DB", "Assertions.json"))
+	if err != nil {
+		return false
+	}
+
+	var assertions struct {
+		Data []struct {
+			StoreAssertionRecords []json.RawMessage `json:"storeAssertionRecords"`
+		} `json:"data"`
+	}
+	if json.Unmarshal(content, &assertions) != nil {
+		return false
+	}
+	for _, data := range assertions.Data {
+		if len(data.StoreAssertionRecords) > 0 {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,153 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+
+	"fyne.io/fyne/v2"
+	"github.com/godbus/dbus/v5"
+)
+
+const (
+	notificationsService   = "org.freedesktop.Notifications"
+	notificationsInterface = "org.freedesktop.Notifications"
+	notificationsPath      = "/org/freedesktop/Notifications"
+)
+
+var (
+	// Actions of the notifications that are still open, by notification ID
+	openNotifications  = map[uint32]notificationActions{}
+	notificationsMutex sync.Mutex
+	listenForActions   sync.Once
+)
+
+func init() {
+	defaultNotificationBackend = "dbus"
+	isDoNotDisturb = linuxDoNotDisturb
+	notificationBackends["dbus"] = dbusNotification
+	if _, err := exec.LookPath("notify-send"); err == nil {
+		notificationBackends["notify-send"] = notifySend
+	}
+}
+
+func notifySend(notification *fyne.Notification, actions notificationActions) error {
+	notification = withMeetingLink(notification, actions.meetingLink)
+	args := []string{"--app-name=Daily"}
+	switch sound := notificationSound(); sound {
+	case defaultSound:
+	case silentSound:
+		args = append(args, "--hint=boolean:suppress-sound:true")
+	default:
+		args = append(args, "--hint=string:sound-name:"+sound)
+	}
+
+	return exec.Command("notify-send", append(args, notification.Title, notification.Content)...).Run()
+}
+
+// Sends the notification to the desktop's notification server over D-Bus. Clicking the notification of an event shows
+// it in the main window, and notifications of meetings have a Join button to join them and a button to snooze them. It
+// fails if there is no session bus, so Fyne's notifications are used instead
+func dbusNotification(notification *fyne.Notification, actions notificationActions) error {
+	connection, err := dbus.SessionBus()
+	if err != nil {
+		return err
+	}
+	listenForActions.Do(func() { go handleNotificationActions(connection) })
+
+	var actionNames []string
+	if actions.show != nil {
+		actionNames = append(actionNames, "default", message("show-event"))
+	}
+	if actions.meetingLink != "" {
+		if actions.show == nil {
+			actionNames = append(actionNames, "default", message("join-meeting"))
+		}
+		actionNames = append(actionNames, "join", message("join-meeting"))
+	}
+	if actions.snooze != nil {
+		actionNames = append(actionNames, "snooze", fmt.Sprintf(message("snooze-reminder"), snoozeReminderMinutes()))
+	}
+	// the sound is only a hint so servers that don't support sounds ignore it
+	hints := map[string]dbus.Variant{}
+	switch sound := notificationSound(); sound {
+	case defaultSound:
+	case silentSound:
+		hints["suppress-sound"] = dbus.MakeVariant(true)
+	default:
+		hints["sound-name"] = dbus.MakeVariant(sound)
+	}
+	var id uint32
+	err = connection.Object(notificationsService, notificationsPath).
+		Call(notificationsInterface+".Notify", 0, "Daily", uint32(0), "", notification.Title, notification.Content, actionNames, hints, int32(-1)).
+		Store(&id)
+	if err != nil {
+		return err
+	}
+
+	if len(actionNames) > 0 {
+		notificationsMutex.Lock()
+		openNotifications[id] = actions
+		notificationsMutex.Unlock()
+	}
+
+	return nil
+}
+
+// Shows the event when the user clicks its notification, opens the meeting with the Join button, or snoozes the
+// reminder
+func handleNotificationActions(connection *dbus.Conn) {
+	err := connection.AddMatchSignal(dbus.WithMatchInterface(notificationsInterface), dbus.WithMatchObjectPath(notificationsPath))
+	if err != nil {
+		slog.Warn("Could not listen for notification actions", "error", err)
+		return
+	}
+	signals := make(chan *dbus.Signal, 10)
+	connection.Signal(signals)
+
+	for signal := range signals {
+		if len(signal.Body) < 2 {
+			continue
+		}
+		id, _ := signal.Body[0].(uint32)
+		notificationsMutex.Lock()
+		actions, found := openNotifications[id]
+		delete(openNotifications, id)
+		notificationsMutex.Unlock()
+		if signal.Name != notificationsInterface+".ActionInvoked" || !found {
+			continue
+		}
+
+		switch action, _ := signal.Body[1].(string); {
+		case action == "snooze" && actions.snooze != nil:
+			slog.Info("Snoozing reminder from notification " + strconv.FormatUint(uint64(id), 10))
+			actions.snooze()
+		case action == "default" && actions.show != nil:
+			slog.Info("Showing event from notification " + strconv.FormatUint(uint64(id), 10))
+			actions.show()
+		case action == "default" || action == "join":
+			slog.Info("Joining meeting from notification " + strconv.FormatUint(uint64(id), 10))
+			if link, err := url.Parse(actions.meetingLink); err == nil {
+				openUrl(link)
+			}
+		}
+	}
+}
+
+// Whether the notification server is inhibited, like KDE's and other servers do in do not disturb mode, or GNOME is
+// set to not show banners
+func linuxDoNotDisturb() bool {
+	if connection, err := dbus.SessionBus(); err == nil {
+		inhibited, err := connection.Object(notificationsService, notificationsPath).GetProperty(notificationsInterface + ".Inhibited")
+		if err == nil && inhibited.Value() == true {
+			return true
+		}
+	}
+
+	output, err := exec.Command("gsettings", "get", "org.gnome.desktop.notifications", "show-banners").Output()
+	return err == nil && strings.TrimSpace(string(output)) == "false"
+}
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/test"
+)
+
+type notificationBackendTest struct {
+	backend        string
+	backendError   error
+	expectedCustom bool
+	expectedFyne   bool
+}
+
+func TestSendNotification(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	originalDefault := defaultNotificationBackend
+	defaultNotificationBackend = "fyne"
+	defer func() { defaultNotificationBackend = originalDefault }()
+
+	var customSent bool
+	var customError error
+	notificationBackends["custom"] = func(*fyne.Notification, notificationActions) error {
+		customSent = true
+		return customError
+	}
+	defer delete(notificationBackends, "custom")
+
+	var notificationBackendTests = []notificationBackendTest{
+		{"", nil, false, true},
+		{"fyne", nil, false, true},
+		{"custom", nil, true, false},
+		{"custom", errors.New("not available"), true, true},
+		{"unknown", nil, false, true},
+	}
+
+	notification := fyne.NewNotification("title", "content")
+	for i, backendTest := range notificationBackendTests {
+		customSent, customError = false, backendTest.backendError
+		if backendTest.backend != "" {
+			dailyApp.Preferences().SetString("notification-backend", backendTest.backend)
+		}
+
+		var expectedFyneNotification *fyne.Notification
+		if backendTest.expectedFyne {
+			expectedFyneNotification = notification
+		}
+		test.AssertNotificationSent(t, expectedFyneNotification, func() { sendNotification(notification, notificationActions{}) })
+		if customSent != backendTest.expectedCustom {
+			t.Errorf("%d. Actual custom backend used = %t doesn't match expected %t", i, customSent, backendTest.expectedCustom)
+		}
+	}
+}
+
+func TestSendNotificationWithMeetingLink(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("notification-backend", "fyne")
+
+	var customLink string
+	notificationBackends["custom"] = func(notification *fyne.Notification, actions notificationActions) error {
+		customLink = actions.meetingLink
+		return nil
+	}
+	defer delete(notificationBackends, "custom")
+
+	notification := fyne.NewNotification("'standup' is starting soon", "5 minutes to event")
+	expected := fyne.NewNotification(notification.Title, notification.Content+"\nhttps://zoom.us/j/1234")
+	test.AssertNotificationSent(t, expected, func() { sendNotification(notification, notificationActions{meetingLink: "https://zoom.us/j/1234"}) })
+
+	dailyApp.Preferences().SetString("notification-backend", "custom")
+	sendNotification(notification, notificationActions{meetingLink: "https://zoom.us/j/1234"})
+	if customLink != "https://zoom.us/j/1234" {
+		t.Errorf("Actual link %q was not passed to the backend", customLink)
+	}
+}
+
+func TestNotifiedReminders(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	originalNow := now
+	defer func() { now = originalNow }()
+	today := time.Date(2024, 11, 18, 9, 0, 0, 0, time.Local)
+	now = func() time.Time { return today }
+
+	if isNotified("standup@5") {
+		t.Errorf("Reminder was notified before marking it")
+	}
+	markNotified("standup@5")
+	markNotified("standup@1")
+	if !isNotified("standup@5") || !isNotified("standup@1") || isNotified("review@5") {
+		t.Errorf("Actual notified reminders %v don't match the marked ones", dailyApp.Preferences().StringList("notified-events"))
+	}
+
+	now = func() time.Time { return today.AddDate(0, 0, 1) }
+	if isNotified("standup@5") {
+		t.Errorf("Reminder of yesterday is still notified")
+	}
+	clearOldNotified()
+	if actual := dailyApp.Preferences().StringList("notified-events"); len(actual) != 0 {
+		t.Errorf("Actual notified reminders %v of yesterday were not cleared", actual)
+	}
+	markNotified("standup@5")
+	if actual := dailyApp.Preferences().StringList("notified-events"); len(actual) != 1 {
+		t.Errorf("Actual notified reminders %v don't match the one of today", actual)
+	}
+}
+
+func TestNotifyOffersActions(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("notification-backend", "custom")
+	defer func(original func() bool) { isDoNotDisturb = original }(isDoNotDisturb)
+	isDoNotDisturb = func() bool { return false }
+
+	var sent []notificationActions
+	notificationBackends["custom"] = func(notification *fyne.Notification, actions notificationActions) error {
+		sent = append(sent, actions)
+		return nil
+	}
+	defer delete(notificationBackends, "custom")
+
+	start := now().Add(5 * time.Minute)
+	meeting := event{id: "standup", title: "standup", start: start, end: start.Add(15 * time.Minute), location: "https://zoom.us/j/1234"}
+	inPerson := event{id: "lunch", title: "lunch", start: start, end: start.Add(time.Hour), location: "Cafeteria"}
+	notify(&meeting, 5*time.Minute, "standup@5")
+	notify(&inPerson, 5*time.Minute, "lunch@5")
+
+	if len(sent) != 2 {
+		t.Fatalf("Actual %d notifications don't match expected 2", len(sent))
+	}
+	if sent[0].meetingLink != meeting.location || sent[0].snooze == nil || sent[0].show == nil {
+		t.Errorf("Actual actions of the meeting don't include joining, snoozing and showing")
+	}
+	if sent[1].meetingLink != "" || sent[1].snooze == nil {
+		t.Errorf("Actual actions of the in-person event don't match expected")
+	}
+	if !isNotified("standup@5") || !isNotified("lunch@5") {
+		t.Errorf("Reminders were not marked as notified")
+	}
+
+	isDoNotDisturb = func() bool { return true }
+	notify(&meeting, time.Minute, "standup@1")
+	if len(sent) != 2 || isNotified("standup@1") {
+		t.Errorf("Reminder was notified in do not disturb mode")
+	}
+}
+
+type notificationSoundTest struct {
+	preference string
+	expected   string
+}
+
+func TestNotificationSound(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var notificationSoundTests = []notificationSoundTest{
+		{"", defaultSound},
+		{"Default", defaultSound},
+		{" SILENT ", silentSound},
+		{"Glass", "Glass"},
+	}
+	for i, test := range notificationSoundTests {
+		dailyApp.Preferences().SetString("notification-sound", test.preference)
+		if actual := notificationSound(); actual != test.expected {
+			t.Errorf("%d. Actual sound %q doesn't match expected %q", i, actual, test.expected)
+		}
+	}
+}
+
+func TestNotifySnoozedRespectsSnoozeAndDoNotDisturb(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("notification-backend", "custom")
+	defer func(original func() bool) { isDoNotDisturb = original }(isDoNotDisturb)
+	isDoNotDisturb = func() bool { return false }
+
+	sent := 0
+	notificationBackends["custom"] = func(notification *fyne.Notification, actions notificationActions) error {
+		sent++
+		return nil
+	}
+	defer delete(notificationBackends, "custom")
+
+	start := now().Add(2 * time.Minute)
+	meeting := event{id: "standup", title: "standup", start: start, end: start.Add(15 * time.Minute)}
+	snoozeNotifications(now().Add(time.Hour))
+	notifySnoozed(meeting, "standup@5")
+	unsnoozeNotifications()
+	isDoNotDisturb = func() bool { return true }
+	notifySnoozed(meeting, "standup@5")
+	if sent != 0 {
+		t.Errorf("Actual %d snoozed reminders were sent while notifications are snoozed or in do not disturb", sent)
+	}
+
+	isDoNotDisturb = func() bool { return false }
+	notifySnoozed(meeting, "standup@5")
+	finished := event{id: "review", title: "review", start: now().Add(-time.Hour), end: now().Add(-time.Minute)}
+	notifySnoozed(finished, "review@5")
+	if sent != 1 {
+		t.Errorf("Actual %d snoozed reminders don't match expected 1", sent)
+	}
+}
@@ -0,0 +1,314 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"fyne.io/fyne/v2"
+	"golang.org/x/oauth2"
+	"golang.org/x/oauth2/microsoft"
+)
+
+const (
+	outlookClientFile = "secrets/outlook.json"
+	outlookTimeFormat = "2006-01-02T15:04:05.9999999"
+)
+
+// Base URL of Microsoft Graph. Tests point it to a fake server
+var outlookGraphUrl = "https://graph.microsoft.com/v1.0"
+
+// Gets the client to call Microsoft Graph with the token. Tests replace it to not need the app registration
+var outlookHttpClient = func(token string) (*http.Client, error) {
+	config, err := createOutlookOAuthConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	tok := &oauth2.Token{}
+	err = json.Unmarshal([]byte(token), tok)
+	if err != nil {
+		slog.Error("Error decoding Outlook token")
+		return nil, err
+	}
+
+	ctx := proxiedContext()
+	tokenSource := &persistingTokenSource{source: config.TokenSource(ctx, tok), last: tok, preference: "outlook-token"}
+	return oauth2.NewClient(ctx, tokenSource), nil
+}
+
+// An event source for the calendar of a Microsoft 365 or Outlook.com account, retrieved with Microsoft Graph
+type outlookCalendar struct {
+	client           *http.Client
+	eventsBuffer     []event
+	requestStartDate time.Time
+	requestEndDate   time.Time
+}
+
+// The fields of a Graph event that are used
+type outlookEvent struct {
+	Id                   string                       `json:"id"`
+	Subject              string                       `json:"subject"`
+	Start                outlookDateTime              `json:"start"`
+	End                  outlookDateTime              `json:"end"`
+	Location             struct{ DisplayName string } `json:"location"`
+	Body                 struct{ Content string }     `json:"body"`
+	WebLink              string                       `json:"webLink"`
+	LastModifiedDateTime time.Time                    `json:"lastModifiedDateTime"`
+	ResponseStatus       struct{ Response string }    `json:"responseStatus"`
+	IsOrganizer          bool                         `json:"isOrganizer"`
+	IsAllDay             bool                         `json:"isAllDay"`
+	IsCancelled          bool                         `json:"isCancelled"`
+	IsReminderOn         bool                         `json:"isReminderOn"`
+	ReminderMinutes      int                          `json:"reminderMinutesBeforeStart"`
+	ShowAs               string                       `json:"showAs"`
+	Type                 string                       `json:"type"`
+	OnlineMeeting        *struct{ JoinUrl string }    `json:"onlineMeeting"`
+}
+
+type outlookDateTime struct {
+	DateTime string `json:"dateTime"`
+	TimeZone string `json:"timeZone"`
+}
+
+// Responses of the user in Graph and their equivalent in Google Calendar
+var outlookResponses = map[string]responseStatus{
+	"organizer":           accepted,
+	"accepted":            accepted,
+	"tentativelyAccepted": tentative,
+	"declined":            declined,
+	"notResponded":        needsAction,
+}
+
+// Gets a token for Outlook with the authorization code flow with PKCE, since the app can't keep a client secret
+func startOutlookOAuthFlow(parent fyne.Window, manual bool) (string, error) {
+	slog.Info("Starting OAuth flow for Outlook. Manual = " + strconv.FormatBool(manual))
+
+	config, err := createOutlookOAuthConfig()
+	if err != nil {
+		slog.Error("Failed to create config", "error", err)
+		return "", err
+	}
+
+	verifier := oauth2.GenerateVerifier()
+	return startOAuthFlow(config, parent, manual, []oauth2.AuthCodeOption{oauth2.S256ChallengeOption(verifier)}, []oauth2.AuthCodeOption{oauth2.VerifierOption(verifier)})
+}
+
+// Creates the config from the client file, which has the client_id of the app registration and optionally the tenant
+func createOutlookOAuthConfig() (*oauth2.Config, error) {
+	clientFile, err := os.ReadFile(outlookClientFile)
+	if err != nil {
+		slog.Error("Unable to read Outlook client file: ", "error", err)
+		return nil, err
+	}
+
+	var client struct {
+		ClientId string `json:"client_id"`
+		Tenant   string `json:"tenant"`
+	}
+	err = json.Unmarshal(clientFile, &client)
+	if err != nil {
+		return nil, err
+	}
+	if client.ClientId == "" {
+		return nil, errors.New("No client_id in " + outlookClientFile)
+	}
+	if client.Tenant == "" {
+		client.Tenant = "common"
+	}
+
+	return &oauth2.Config{
+		ClientID: client.ClientId,
+		Endpoint: microsoft.AzureADEndpoint(client.Tenant),
+		Scopes:   []string{"offline_access", "Calendars.Read"},
+	}, nil
+}
+
+func newOutlookEventSource() (*outlookCalendar, error) {
+	client, err := outlookHttpClient(dailyApp.Preferences().String("outlook-token"))
+	if err != nil {
+		return nil, err
+	}
+
+	return &outlookCalendar{client: client}, nil
+}
+
+func (outlook *outlookCalendar) getEvents(day time.Time, fullRefresh bool) ([]event, bool, error) {
+	halfWindow, threshold := bufferWindow()
+	refreshed := false
+	if outlook.requestStartDate.IsZero() || fullRefresh || startOfDay(day).Before(outlook.requestStartDate.AddDate(0, 0, threshold)) ||
+		!startOfDay(day).Before(outlook.requestEndDate.AddDate(0, 0, -threshold)) {
+		err := outlook.retrieveEventsBetween(day.AddDate(0, 0, -halfWindow), day.AddDate(0, 0, halfWindow))
+		if err != nil {
+			return nil, false, err
+		}
+		refreshed = true
+	}
+
+	var result []event
+	for _, event := range outlook.eventsBuffer {
+		if isOnSameDay(day, event.start) {
+			result = append(result, event)
+		}
+	}
+
+	return result, refreshed, nil
+}
+
+func (outlook *outlookCalendar) getBufferedEvents() []event {
+	return outlook.eventsBuffer
+}
+
+func (outlook *outlookCalendar) getEventsBetween(start time.Time, end time.Time) ([]event, error) {
+	if outlook.requestStartDate.IsZero() || start.Before(outlook.requestStartDate) || end.After(outlook.requestEndDate) {
+		bufferStart, bufferEnd := start, end
+		if !outlook.requestStartDate.IsZero() {
+			bufferStart, bufferEnd = minTime(outlook.requestStartDate, start), maxTime(outlook.requestEndDate, end)
+		}
+		// the end is rounded down to the start of its day
+		err := outlook.retrieveEventsBetween(bufferStart, bufferEnd.AddDate(0, 0, 1))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var result []event
+	for _, event := range outlook.eventsBuffer {
+		if !event.start.Before(start) && event.start.Before(end) {
+			result = append(result, event)
+		}
+	}
+
+	return result, nil
+}
+
+func (outlook *outlookCalendar) retrieveEventsBetween(start time.Time, end time.Time) error {
+	start, end = startOfDay(start), startOfDay(end)
+	slog.Info("Retrieving Outlook events between " + start.Format(time.RFC3339) + " and " + end.Format(time.RFC3339))
+
+	query := url.Values{}
+	query.Set("startDateTime", start.UTC().Format(time.RFC3339))
+	query.Set("endDateTime", end.UTC().Format(time.RFC3339))
+	query.Set("$select", "id,subject,start,end,location,body,webLink,lastModifiedDateTime,responseStatus,isOrganizer,isAllDay,isCancelled,isReminderOn,reminderMinutesBeforeStart,showAs,type,onlineMeeting")
+	query.Set("$orderby", "start/dateTime")
+	query.Set("$top", "100")
+	var items []outlookEvent
+	// busy calendars don't fit in a single page so all of them are retrieved
+	for pageUrl := outlookGraphUrl + "/me/calendarView?" + query.Encode(); pageUrl != ""; {
+		var page struct {
+			Value    []outlookEvent `json:"value"`
+			NextLink string         `json:"@odata.nextLink"`
+		}
+		err := outlook.get(pageUrl, &page)
+		if err != nil {
+			return err
+		}
+		items = append(items, page.Value...)
+		pageUrl = page.NextLink
+	}
+	slog.Debug("Retrieved " + strconv.Itoa(len(items)) + " Outlook event(s) successfully")
+
+	events, err := convertOutlookEvents(items)
+	if err != nil {
+		return err
+	}
+	outlook.eventsBuffer = events
+	outlook.requestStartDate = start
+	outlook.requestEndDate = end
+
+	return nil
+}
+
+func (outlook *outlookCalendar) get(requestUrl string, result any) error {
+	request, err := http.NewRequest(http.MethodGet, requestUrl, nil)
+	if err != nil {
+		return err
+	}
+	// times are returned in UTC instead of the time zone of each event
+	request.Header.Set("Prefer", `outlook.timezone="UTC"`)
+
+	response, err := outlook.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Microsoft Graph responded %s", response.Status)
+	}
+
+	return json.NewDecoder(response.Body).Decode(result)
+}
+
+// Converts the events from Graph, leaving out cancelled and all-day ones like in the other sources
+func convertOutlookEvents(items []outlookEvent) ([]event, error) {
+	var result []event
+	for _, item := range items {
+		if item.IsCancelled || item.IsAllDay {
+			continue
+		}
+
+		start, err := parseOutlookTime(item.Start)
+		if err != nil {
+			return nil, err
+		}
+		end, err := parseOutlookTime(item.End)
+		if err != nil {
+			return nil, err
+		}
+
+		response := outlookResponses[item.ResponseStatus.Response]
+		newEvent := event{
+			id:         item.Id,
+			title:      item.Subject,
+			start:      start,
+			end:        end,
+			location:   item.Location.DisplayName,
+			details:    item.Body.Content,
+			notifiable: isNotifiableResponse(response) && item.ShowAs != "free",
+			response:   response,
+			htmlLink:   item.WebLink,
+			updated:    item.LastModifiedDateTime,
+			source:     "Outlook",
+			kind:       outlookSource,
+			organizer:  item.IsOrganizer,
+			recurring:  item.Type == "occurrence" || item.Type == "exception",
+			free:       item.ShowAs == "free",
+			tags:       extractTags(item.Body.Content),
+			dialIn:     parseDialIn(cleanEventDetails(item.Body.Content)),
+		}
+		if item.IsReminderOn {
+			newEvent.reminders = []int{item.ReminderMinutes}
+		}
+		if item.OnlineMeeting != nil && item.OnlineMeeting.JoinUrl != "" {
+			newEvent.location = item.OnlineMeeting.JoinUrl
+		}
+		newEvent.useMeetingLinkInDetails()
+		if newEvent.dialIn.url == "" && newEvent.isVideoMeeting() {
+			newEvent.dialIn.url = newEvent.location
+		}
+		result = append(result, newEvent)
+	}
+
+	return result, nil
+}
+
+// Parses a Graph time, which has no offset but comes with the name of its time zone
+func parseOutlookTime(dateTime outlookDateTime) (time.Time, error) {
+	location := time.UTC
+	if dateTime.TimeZone != "" && !strings.EqualFold(dateTime.TimeZone, "UTC") {
+		zoneLocation, err := time.LoadLocation(dateTime.TimeZone)
+		if err != nil {
+			return time.Time{}, err
+		}
+		location = zoneLocation
+	}
+
+	return time.ParseInLocation(outlookTimeFormat, dateTime.DateTime, location)
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+const outlookFirstPage = `{
+	"value": [
+		{
+			"id": "review", "subject": "Design review",
+			"start": {"dateTime": "2024-11-18T15:00:00.0000000", "timeZone": "UTC"},
+			"end": {"dateTime": "2024-11-18T16:00:00.0000000", "timeZone": "UTC"},
+			"location": {"displayName": "Microsoft Teams Meeting"},
+			"body": {"content": "<p>Agenda #design</p>"},
+			"responseStatus": {"response": "tentativelyAccepted"},
+			"isReminderOn": true, "reminderMinutesBeforeStart": 15, "showAs": "tentative", "type": "singleInstance",
+			"onlineMeeting": {"joinUrl": "https://teams.microsoft.com/l/meetup-join/abc"},
+			"lastModifiedDateTime": "2024-11-10T09:00:00Z"
+		},
+		{
+			"id": "holiday", "subject": "Holiday", "isAllDay": true,
+			"start": {"dateTime": "2024-11-18T00:00:00.0000000", "timeZone": "UTC"},
+			"end": {"dateTime": "2024-11-19T00:00:00.0000000", "timeZone": "UTC"}
+		}
+	],
+	"@odata.nextLink": "NEXT_PAGE"
+}`
+
+const outlookSecondPage = `{
+	"value": [
+		{
+			"id": "standup", "subject": "Standup",
+			"start": {"dateTime": "2024-11-18T14:00:00.0000000", "timeZone": "UTC"},
+			"end": {"dateTime": "2024-11-18T14:15:00.0000000", "timeZone": "UTC"},
+			"location": {"displayName": "Room 1"},
+			"body": {"content": "Remote: <a href=\"https://company.zoom.us/j/5555\">Zoom</a>"},
+			"responseStatus": {"response": "organizer"}, "isOrganizer": true, "showAs": "busy", "type": "occurrence"
+		},
+		{
+			"id": "lunch", "subject": "Lunch", "isCancelled": true,
+			"start": {"dateTime": "2024-11-18T17:00:00.0000000", "timeZone": "UTC"},
+			"end": {"dateTime": "2024-11-18T18:00:00.0000000", "timeZone": "UTC"}
+		},
+		{
+			"id": "focus", "subject": "Focus time",
+			"start": {"dateTime": "2024-11-18T18:00:00.0000000", "timeZone": "UTC"},
+			"end": {"dateTime": "2024-11-18T20:00:00.0000000", "timeZone": "UTC"},
+			"responseStatus": {"response": "organizer"}, "showAs": "free"
+		}
+	]
+}`
+
+func TestOutlookCalendarGetEvents(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var requests []*http.Request
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		requests = append(requests, request)
+		if request.URL.Query().Get("page") == "2" {
+			_, _ = writer.Write([]byte(outlookSecondPage))
+			return
+		}
+		_, _ = writer.Write([]byte(strings.Replace(outlookFirstPage, "NEXT_PAGE", server.URL+"/me/calendarView?page=2", 1)))
+	}))
+	defer server.Close()
+	originalUrl, originalClient := outlookGraphUrl, outlookHttpClient
+	outlookGraphUrl = server.URL
+	outlookHttpClient = func(token string) (*http.Client, error) { return server.Client(), nil }
+	defer func() { outlookGraphUrl, outlookHttpClient = originalUrl, originalClient }()
+
+	source, err := newOutlookEventSource()
+	if err != nil {
+		t.Fatal("Error creating event source", err)
+	}
+	day := time.Date(2024, 11, 18, 12, 0, 0, 0, time.UTC)
+	events, refreshed, err := source.getEvents(day, false)
+	if err != nil {
+		t.Fatal("Error getting events", err)
+	}
+	if !refreshed || len(requests) != 2 || requests[0].Header.Get("Prefer") != `outlook.timezone="UTC"` {
+		t.Errorf("Actual %d requests don't match the expected 2 pages in UTC", len(requests))
+	}
+
+	var titles []string
+	for _, event := range events {
+		titles = append(titles, event.title)
+	}
+	if !slices.Equal(titles, []string{"Design review", "Standup", "Focus time"}) {
+		t.Fatalf("Actual %q don't match the expected events", titles)
+	}
+	review, standup, focus := events[0], events[1], events[2]
+	if review.location != "https://teams.microsoft.com/l/meetup-join/abc" || review.dialIn.url != review.location {
+		t.Errorf("Actual location %q is not the Teams link", review.location)
+	}
+	if review.response != tentative || !slices.Equal(review.reminders, []int{15}) || !slices.Equal(review.tags, []string{"design"}) {
+		t.Errorf("Actual response %q, reminders %v or tags %v don't match expected", review.response, review.reminders, review.tags)
+	}
+	if !review.start.Equal(time.Date(2024, 11, 18, 15, 0, 0, 0, time.UTC)) {
+		t.Errorf("Actual start %v doesn't match expected", review.start)
+	}
+	if standup.location != "https://company.zoom.us/j/5555" {
+		t.Errorf("Actual location %q is not the Zoom link in the body", standup.location)
+	}
+	if standup.response != accepted || !standup.organizer || !standup.recurring || !standup.notifiable || standup.kind != outlookSource {
+		t.Errorf("Actual standup %+v doesn't match expected", standup)
+	}
+	if focus.notifiable || !focus.free || review.free {
+		t.Errorf("Actual free event %+v is notifiable or not free", focus)
+	}
+
+	if _, refreshed, _ := source.getEvents(day.AddDate(0, 0, 1), false); refreshed {
+		t.Errorf("Events were retrieved again within the buffer")
+	}
+}
+
+func TestOutlookCalendarBufferStaysBounded(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var ranges []string
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		ranges = append(ranges, request.URL.Query().Get("startDateTime")+" - "+request.URL.Query().Get("endDateTime"))
+		_, _ = writer.Write([]byte(`{"value": []}`))
+	}))
+	defer server.Close()
+	originalUrl, originalClient := outlookGraphUrl, outlookHttpClient
+	outlookGraphUrl = server.URL
+	outlookHttpClient = func(token string) (*http.Client, error) { return server.Client(), nil }
+	defer func() { outlookGraphUrl, outlookHttpClient = originalUrl, originalClient }()
+
+	source, err := newOutlookEventSource()
+	if err != nil {
+		t.Fatal("Error creating event source", err)
+	}
+	halfWindow, _ := bufferWindow()
+	day := time.Date(2024, 11, 18, 12, 0, 0, 0, time.UTC)
+	for _, offset := range []int{0, 30, 60} {
+		if _, _, err := source.getEvents(day.AddDate(0, 0, offset), false); err != nil {
+			t.Fatal("Error getting events", err)
+		}
+	}
+
+	last := day.AddDate(0, 0, 60)
+	expected := startOfDay(last.AddDate(0, 0, -halfWindow)).UTC().Format(time.RFC3339) + " - " + startOfDay(last.AddDate(0, 0, halfWindow)).UTC().Format(time.RFC3339)
+	if len(ranges) != 3 || ranges[2] != expected {
+		t.Errorf("Actual ranges %q don't end with the expected %q around the last day", ranges, expected)
+	}
+}
+
+type outlookTimeTest struct {
+	dateTime      outlookDateTime
+	expected      time.Time
+	expectedError bool
+}
+
+func TestParseOutlookTime(t *testing.T) {
+	paris, _ := time.LoadLocation("Europe/Paris")
+	var outlookTimeTests = []outlookTimeTest{
+		{outlookDateTime{"2024-11-18T15:00:00.0000000", "UTC"}, time.Date(2024, 11, 18, 15, 0, 0, 0, time.UTC), false},
+		{outlookDateTime{"2024-11-18T15:00:00", ""}, time.Date(2024, 11, 18, 15, 0, 0, 0, time.UTC), false},
+		{outlookDateTime{"2024-11-18T15:00:00.0000000", "Europe/Paris"}, time.Date(2024, 11, 18, 15, 0, 0, 0, paris), false},
+		{outlookDateTime{"2024-11-18T15:00:00.0000000", "Nowhere/Unknown"}, time.Time{}, true},
+		{outlookDateTime{"tomorrow", "UTC"}, time.Time{}, true},
+	}
+
+	for i, test := range outlookTimeTests {
+		actual, err := parseOutlookTime(test.dateTime)
+		if (err != nil) != test.expectedError {
+			t.Errorf("%d. Actual error %v doesn't match expected error = %t", i, err, test.expectedError)
+		}
+		if !actual.Equal(test.expected) {
+			t.Errorf("%d. Actual %v doesn't match expected %v", i, actual, test.expected)
+		}
+	}
+}
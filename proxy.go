@@ -0,0 +1,40 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"golang.org/x/oauth2"
+)
+
+// Transport of all the outbound requests. It is shared so that connections are reused, and the proxy is looked up on
+// each request so that changes to the preference apply right away
+var proxiedTransport = newProxiedTransport()
+
+func newProxiedTransport() *http.Transport {
+	result := http.DefaultTransport.(*http.Transport).Clone()
+	result.Proxy = proxyFor
+	return result
+}
+
+// Gets the proxy in the proxy-url preference or, if it's not set, the one in the HTTPS_PROXY and HTTP_PROXY environment
+// variables
+func proxyFor(request *http.Request) (*url.URL, error) {
+	proxyUrl := strings.TrimSpace(dailyApp.Preferences().String("proxy-url"))
+	if proxyUrl == "" {
+		return http.ProxyFromEnvironment(request)
+	}
+	if !strings.Contains(proxyUrl, "://") {
+		proxyUrl = "http://" + proxyUrl
+	}
+
+	return url.Parse(proxyUrl)
+}
+
+// Gets a context that makes the oauth2 library go through the proxy, both for its own requests and as the base of the
+// clients it creates
+func proxiedContext() context.Context {
+	return context.WithValue(context.Background(), oauth2.HTTPClient, &http.Client{Transport: proxiedTransport})
+}
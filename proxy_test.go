@@ -0,0 +1,57 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+type proxyTest struct {
+	preference string
+	expected   string
+}
+
+func TestProxyFor(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var proxyTests = []proxyTest{
+		{"http://proxy.corp:3128", "http://proxy.corp:3128"},
+		{"proxy.corp:3128", "http://proxy.corp:3128"},
+		{" https://proxy.corp ", "https://proxy.corp"},
+	}
+
+	for i, test := range proxyTests {
+		dailyApp.Preferences().SetString("proxy-url", test.preference)
+		request, _ := http.NewRequest(http.MethodGet, "https://www.googleapis.com/calendar/v3", nil)
+		actual, err := proxyFor(request)
+		if err != nil || actual == nil || actual.String() != test.expected {
+			t.Errorf("%d. Actual proxy %v (error %v) doesn't match expected %s", i, actual, err, test.expected)
+		}
+	}
+}
+
+func TestIcsFeedGoesThroughProxy(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var proxiedUrls []string
+	proxy := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		proxiedUrls = append(proxiedUrls, request.URL.String())
+		_, _ = writer.Write([]byte(testFeed))
+	}))
+	defer proxy.Close()
+	dailyApp.Preferences().SetString("proxy-url", proxy.URL)
+
+	source := newIcsEventSource("http://calendar.example.invalid/school.ics")
+	_, _, err := source.getEvents(time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC), false)
+	if err != nil {
+		t.Fatal("Error getting events", err)
+	}
+	if len(proxiedUrls) != 1 || proxiedUrls[0] != "http://calendar.example.invalid/school.ics" {
+		t.Errorf("Actual proxied requests %q don't match the feed", proxiedUrls)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+)
+
+// Emoji of the Slack status set during meetings
+const slackStatusEmoji = ":calendar:"
+
+// Base URL of the Slack Web API. Tests point it to a fake server
+var slackApiUrl = "https://slack.com/api"
+
+var slackClient = &http.Client{Transport: proxiedTransport, Timeout: 30 * time.Second}
+
+// Returned when the token lacks a scope for the method, like tokens created before the availability was checked
+var errSlackMissingScope = errors.New("Slack error: missing_scope")
+
+// The custom status of a Slack profile
+type slackProfile struct {
+	StatusText       string `json:"status_text"`
+	StatusEmoji      string `json:"status_emoji"`
+	StatusExpiration int64  `json:"status_expiration"`
+}
+
+// Sets the Slack status of the user with the token in the slack-token preference while they are in a meeting, unless it
+// is disabled in the slack-enabled preference, and pauses Slack notifications until the meeting ends. Both expire at
+// the end of the meeting and are cleared if it ends early. If the user set a status or availability of their own, it is
+// left alone
+func updateSlackStatus(events []event) {
+	token := dailyApp.Preferences().String("slack-token")
+	if token == "" || !dailyApp.Preferences().BoolWithFallback("slack-enabled", true) || shuttingDown.Load() {
+		return
+	}
+
+	// blocks like lunch don't change the status unless the user wants them to
+	meeting := findCurrentMeeting(events, dailyApp.Preferences().BoolWithFallback("slack-virtual-only", true))
+	statusEvent := dailyApp.Preferences().String("slack-status-event")
+	if statusEvent != "" && (meeting == nil || meeting.id != statusEvent) {
+		clearSlackStatus(token)
+	}
+	if meeting == nil || statusEvent == meeting.id {
+		return
+	}
+
+	profile, err := getSlackProfile(token)
+	if err != nil {
+		slog.Error("Could not get the Slack status", "error", err)
+		return
+	}
+	if profile.StatusText != "" && profile.StatusText != dailyApp.Preferences().String("slack-status-text") {
+		slog.Debug("Not updating the Slack status because it was set by the user: " + profile.StatusText)
+		return
+	}
+	manual, err := isSlackAvailabilityManual(token)
+	if errors.Is(err, errSlackMissingScope) {
+		// testing the connection tells the user which scope to add
+		slog.Debug("Not checking the Slack availability", "error", err)
+	} else if err != nil {
+		slog.Error("Could not get the Slack availability", "error", err)
+		return
+	}
+	if manual {
+		slog.Debug("Not updating the Slack status because the user set their availability")
+		return
+	}
+
+	slog.Info("Setting the Slack status for '" + meeting.title + "'")
+	statusText := createSlackStatusText(meeting)
+	err = setSlackStatus(token, slackProfile{StatusText: statusText, StatusEmoji: slackStatusEmoji, StatusExpiration: meeting.end.Unix()})
+	if err == nil {
+		minutes := int(meeting.end.Sub(now()).Round(time.Minute).Minutes())
+		err = callSlack(token, http.MethodPost, "dnd.setSnooze", url.Values{"num_minutes": {strconv.Itoa(max(minutes, 1))}}, nil)
+	}
+	if err != nil {
+		slog.Error("Could not set the Slack status", "error", err)
+		return
+	}
+	dailyApp.Preferences().SetString("slack-status-event", meeting.id)
+	dailyApp.Preferences().SetString("slack-status-text", statusText)
+	dailyApp.Preferences().SetString("slack-status-until", meeting.end.Format(time.RFC3339))
+}
+
+// Clears the status set for a meeting that is no longer ongoing before its expected end, like when the user left it
+// early or it was cancelled. Otherwise the status expired already. A status changed by the user is left alone
+func clearSlackStatus(token string) {
+	until, _ := time.Parse(time.RFC3339, dailyApp.Preferences().String("slack-status-until"))
+	dailyApp.Preferences().RemoveValue("slack-status-event")
+	dailyApp.Preferences().RemoveValue("slack-status-until")
+	if !now().Before(until) {
+		return
+	}
+
+	profile, err := getSlackProfile(token)
+	if err != nil {
+		slog.Error("Could not get the Slack status", "error", err)
+		return
+	}
+	if profile.StatusText != dailyApp.Preferences().String("slack-status-text") {
+		slog.Debug("Not clearing the Slack status because it was changed by the user: " + profile.StatusText)
+		return
+	}
+
+	slog.Info("Clearing the Slack status of a meeting that ended early")
+	err = setSlackStatus(token, slackProfile{})
+	if err == nil {
+		err = callSlack(token, http.MethodPost, "dnd.endSnooze", nil, nil)
+	}
+	if err != nil {
+		slog.Error("Could not clear the Slack status", "error", err)
+	}
+}
+
+// Checks that the token can read the Slack status and availability. The result describes the current status to show to
+// the user
+func testSlackConnection(token string) (string, error) {
+	if token == "" {
+		return "", errors.New(message("slack-token-missing"))
+	}
+	profile, err := getSlackProfile(token)
+	if err != nil {
+		return "", err
+	}
+	_, err = isSlackAvailabilityManual(token)
+	if errors.Is(err, errSlackMissingScope) {
+		return "", errors.New(message("slack-missing-scope") + "\n" + err.Error())
+	}
+	if err != nil {
+		return "", err
+	}
+
+	status := strings.TrimSpace(profile.StatusEmoji + " " + profile.StatusText)
+	if status == "" {
+		status = message("slack-no-status")
+	}
+	return fmt.Sprintf(message("slack-connected"), status), nil
+}
+
+// Gets the status text for the meeting from the Go template in the slack-status-template preference, like
+// "In a meeting: {{.Title}}". The template gets the Title, Start and End of the meeting. Without a valid template, a
+// fixed text is used
+func createSlackStatusText(meeting *event) string {
+	fallback := message("slack-status")
+	text := dailyApp.Preferences().String("slack-status-template")
+	if text == "" {
+		return fallback
+	}
+
+	statusTemplate, err := template.New("slack-status").Parse(text)
+	if err != nil {
+		slog.Warn("Invalid slack-status-template. Using the default status", "error", err)
+		return fallback
+	}
+	var result strings.Builder
+	data := struct {
+		Title      string
+		Start, End time.Time
+	}{meeting.title, meeting.start, meeting.end}
+	err = statusTemplate.Execute(&result, data)
+	if err != nil {
+		slog.Warn("Could not apply slack-status-template. Using the default status", "error", err)
+		return fallback
+	}
+
+	return result.String()
+}
+
+// Whether the user set themselves away or paused their notifications. Only users whose availability is automatic
+// are changed. The snooze of a meeting is ended before this is checked for the next one, so one that is active was set
+// by the user
+func isSlackAvailabilityManual(token string) (bool, error) {
+	var presence struct {
+		ManualAway bool `json:"manual_away"`
+	}
+	err := callSlack(token, http.MethodGet, "users.getPresence", nil, &presence)
+	if err != nil || presence.ManualAway {
+		return presence.ManualAway, err
+	}
+
+	var dnd struct {
+		SnoozeEnabled bool `json:"snooze_enabled"`
+	}
+	err = callSlack(token, http.MethodGet, "dnd.info", nil, &dnd)
+
+	return dnd.SnoozeEnabled, err
+}
+
+func getSlackProfile(token string) (slackProfile, error) {
+	var response struct {
+		Profile slackProfile `json:"profile"`
+	}
+	err := callSlack(token, http.MethodGet, "users.profile.get", nil, &response)
+
+	return response.Profile, err
+}
+
+func setSlackStatus(token string, profile slackProfile) error {
+	body, err := json.Marshal(map[string]slackProfile{"profile": profile})
+	if err != nil {
+		return err
+	}
+
+	return callSlackWithBody(token, "users.profile.set", body, nil)
+}
+
+// Calls a method of the Slack Web API with the parameters in the query or, for POST, as a form
+func callSlack(token string, httpMethod string, method string, parameters url.Values, result any) error {
+	requestUrl := slackApiUrl + "/" + method
+	var body io.Reader
+	if httpMethod == http.MethodPost {
+		body = strings.NewReader(parameters.Encode())
+	} else if len(parameters) > 0 {
+		requestUrl += "?" + parameters.Encode()
+	}
+	request, err := http.NewRequest(httpMethod, requestUrl, body)
+	if err != nil {
+		return err
+	}
+	if httpMethod == http.MethodPost {
+		request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	}
+
+	return doSlackRequest(token, request, result)
+}
+
+// Calls a method of the Slack Web API that takes JSON
+func callSlackWithBody(token string, method string, body []byte, result any) error {
+	request, err := http.NewRequest(http.MethodPost, slackApiUrl+"/"+method, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json; charset=utf-8")
+
+	return doSlackRequest(token, request, result)
+}
+
+// Sends the request and decodes the response into the result, if any. Slack reports errors in the body with ok set
+// to false
+func doSlackRequest(token string, request *http.Request, result any) error {
+	request.Header.Set("Authorization", "Bearer "+token)
+	response, err := slackClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode != http.StatusOK {
+		return fmt.Errorf("Slack responded %s", response.Status)
+	}
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	var status struct {
+		Ok     bool   `json:"ok"`
+		Error  string `json:"error"`
+		Needed string `json:"needed"`
+	}
+	err = json.Unmarshal(content, &status)
+	if err != nil {
+		return err
+	}
+	if status.Error == "missing_scope" {
+		return fmt.Errorf("%w %s", errSlackMissingScope, status.Needed)
+	}
+	if !status.Ok {
+		return errors.New("Slack error: " + status.Error)
+	}
+	if result == nil {
+		return nil
+	}
+
+	return json.Unmarshal(content, result)
+}
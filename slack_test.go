@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+// A fake Slack Web API that keeps the status and counts the snoozes
+type fakeSlack struct {
+	profile      slackProfile
+	snoozes      []string
+	endedSnoozes int
+	manualAway   bool
+	snoozed      bool
+	// whether the token was created without the scopes to read the availability
+	missingScopes bool
+}
+
+func startFakeSlack(t *testing.T, initialStatus string) *fakeSlack {
+	slack := &fakeSlack{profile: slackProfile{StatusText: initialStatus}}
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if request.Header.Get("Authorization") != "Bearer xoxp-test" {
+			_, _ = writer.Write([]byte(`{"ok": false, "error": "invalid_auth"}`))
+			return
+		}
+		if slack.missingScopes && (request.URL.Path == "/users.getPresence" || request.URL.Path == "/dnd.info") {
+			_, _ = writer.Write([]byte(`{"ok": false, "error": "missing_scope", "needed": "users:read"}`))
+			return
+		}
+		switch request.URL.Path {
+		case "/users.profile.get":
+			_ = json.NewEncoder(writer).Encode(map[string]any{"ok": true, "profile": slack.profile})
+		case "/users.profile.set":
+			var body struct{ Profile slackProfile }
+			_ = json.NewDecoder(request.Body).Decode(&body)
+			slack.profile = body.Profile
+			_, _ = writer.Write([]byte(`{"ok": true}`))
+		case "/dnd.setSnooze":
+			_ = request.ParseForm()
+			slack.snoozes = append(slack.snoozes, request.PostForm.Get("num_minutes"))
+			slack.snoozed = true
+			_, _ = writer.Write([]byte(`{"ok": true}`))
+		case "/users.getPresence":
+			_ = json.NewEncoder(writer).Encode(map[string]any{"ok": true, "presence": "away", "manual_away": slack.manualAway})
+		case "/dnd.info":
+			_ = json.NewEncoder(writer).Encode(map[string]any{"ok": true, "snooze_enabled": slack.snoozed})
+		case "/dnd.endSnooze":
+			slack.snoozed = false
+			slack.endedSnoozes++
+			_, _ = writer.Write([]byte(`{"ok": true}`))
+		default:
+			writer.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	originalUrl := slackApiUrl
+	slackApiUrl = server.URL
+	t.Cleanup(func() { slackApiUrl = originalUrl })
+
+	return slack
+}
+
+func TestUpdateSlackStatus(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("slack-token", "xoxp-test")
+	slack := startFakeSlack(t, "")
+
+	start := now().Add(-10 * time.Minute)
+	meeting := event{id: "standup", title: "Standup", start: start, end: start.Add(40 * time.Minute), location: "https://zoom.us/j/1234"}
+	declinedMeeting := event{id: "review", title: "Review", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/2345", response: declined}
+
+	updateSlackStatus([]event{declinedMeeting})
+	if slack.profile.StatusText != "" {
+		t.Fatalf("Actual status %q was set for a declined meeting", slack.profile.StatusText)
+	}
+
+	updateSlackStatus([]event{declinedMeeting, meeting})
+	if slack.profile.StatusText != message("slack-status") || slack.profile.StatusExpiration != meeting.end.Unix() {
+		t.Errorf("Actual status %+v doesn't match expected until the end of the meeting", slack.profile)
+	}
+	if len(slack.snoozes) != 1 || slack.snoozes[0] != "30" {
+		t.Errorf("Actual snoozes %v don't match expected [30]", slack.snoozes)
+	}
+
+	updateSlackStatus([]event{meeting})
+	if len(slack.snoozes) != 1 {
+		t.Errorf("Status was set again for the same meeting")
+	}
+
+	// the user left early and declined the rest of the meeting
+	meeting.response = declined
+	updateSlackStatus([]event{meeting})
+	if slack.profile.StatusText != "" || slack.endedSnoozes != 1 {
+		t.Errorf("Actual status %q or %d ended snoozes don't match expected after the meeting ended early", slack.profile.StatusText, slack.endedSnoozes)
+	}
+	if dailyApp.Preferences().String("slack-status-event") != "" {
+		t.Errorf("Meeting of the status was not forgotten")
+	}
+}
+
+func TestUpdateSlackStatusKeepsUserStatus(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("slack-token", "xoxp-test")
+	slack := startFakeSlack(t, "Out sick")
+
+	start := now().Add(-10 * time.Minute)
+	updateSlackStatus([]event{{id: "standup", title: "Standup", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234"}})
+	if slack.profile.StatusText != "Out sick" || len(slack.snoozes) != 0 {
+		t.Errorf("Actual status %q or snoozes %v overrode the status of the user", slack.profile.StatusText, slack.snoozes)
+	}
+}
+
+func TestUpdateSlackStatusIgnoresBlockedAndFreeEvents(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("slack-token", "xoxp-test")
+	dailyApp.Preferences().SetStringList("title-blocklist", []string{"^Coffee chat$"})
+	slack := startFakeSlack(t, "")
+
+	start := now().Add(-10 * time.Minute)
+	events := []event{
+		{id: "coffee", title: "Coffee chat", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234"},
+		{id: "office-hours", title: "Office hours", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/2345", free: true},
+	}
+	updateSlackStatus(dimBlockedEvents(events))
+	if slack.profile.StatusText != "" || len(slack.snoozes) != 0 {
+		t.Errorf("Actual status %q or snoozes %v were set for blocked and free events", slack.profile.StatusText, slack.snoozes)
+	}
+}
+
+type manualAvailabilityTest struct {
+	manualAway bool
+	snoozed    bool
+}
+
+func TestUpdateSlackStatusKeepsUserAvailability(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("slack-token", "xoxp-test")
+
+	start := now().Add(-10 * time.Minute)
+	meeting := event{id: "standup", title: "Standup", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234"}
+	var manualAvailabilityTests = []manualAvailabilityTest{
+		{true, false},
+		{false, true},
+	}
+	for i, test := range manualAvailabilityTests {
+		slack := startFakeSlack(t, "")
+		slack.manualAway, slack.snoozed = test.manualAway, test.snoozed
+		updateSlackStatus([]event{meeting})
+		if slack.profile.StatusText != "" || len(slack.snoozes) != 0 {
+			t.Errorf("%d. Actual status %q or snoozes %v overrode the availability of the user", i, slack.profile.StatusText, slack.snoozes)
+		}
+	}
+}
+
+func TestUpdateSlackStatusWithoutAvailabilityScopes(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("slack-token", "xoxp-test")
+	slack := startFakeSlack(t, "")
+	slack.missingScopes = true
+
+	start := now().Add(-10 * time.Minute)
+	updateSlackStatus([]event{{id: "standup", title: "Standup", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234"}})
+	if slack.profile.StatusText != message("slack-status") {
+		t.Errorf("Actual status %q was not set without the availability scopes", slack.profile.StatusText)
+	}
+}
+
+func TestUpdateSlackStatusDisabled(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	dailyApp.Preferences().SetString("slack-token", "xoxp-test")
+	dailyApp.Preferences().SetBool("slack-enabled", false)
+	slack := startFakeSlack(t, "")
+
+	start := now().Add(-10 * time.Minute)
+	updateSlackStatus([]event{{id: "standup", title: "Standup", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234"}})
+	if slack.profile.StatusText != "" || len(slack.snoozes) != 0 {
+		t.Errorf("Actual status %q or snoozes %v were set while disabled", slack.profile.StatusText, slack.snoozes)
+	}
+}
+
+func TestTestSlackConnection(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	slack := startFakeSlack(t, "Out sick")
+
+	if actual, err := testSlackConnection("xoxp-test"); err != nil || actual != "Connected. Current status: Out sick" {
+		t.Errorf("Actual result %q or error %v don't match expected", actual, err)
+	}
+	if _, err := testSlackConnection("wrong"); err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Errorf("Actual error %v doesn't match expected invalid_auth", err)
+	}
+	if _, err := testSlackConnection(""); err == nil {
+		t.Errorf("Missing token was not reported")
+	}
+	slack.missingScopes = true
+	if _, err := testSlackConnection("xoxp-test"); err == nil || !strings.Contains(err.Error(), "users:read") {
+		t.Errorf("Actual error %v doesn't report the missing scope", err)
+	}
+}
+
+type slackStatusTextTest struct {
+	template string
+	expected string
+}
+
+func TestCreateSlackStatusText(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	start := time.Date(2024, 11, 18, 14, 0, 0, 0, time.UTC)
+	meeting := event{title: "Standup", start: start, end: start.Add(15 * time.Minute)}
+	var slackStatusTextTests = []slackStatusTextTest{
+		{"", "In a meeting"},
+		{"In a meeting: {{.Title}}", "In a meeting: Standup"},
+		{`{{.Title}} until {{.End.Format "3:04PM"}}`, "Standup until 2:15PM"},
+		{"{{.Title", "In a meeting"},
+		{"{{.Organizer}}", "In a meeting"},
+	}
+	for i, test := range slackStatusTextTests {
+		dailyApp.Preferences().SetString("slack-status-template", test.template)
+		if actual := createSlackStatusText(&meeting); actual != test.expected {
+			t.Errorf("%d. Actual status %q doesn't match expected %q", i, actual, test.expected)
+		}
+	}
+}
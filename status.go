@@ -0,0 +1,163 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+var webhookClient = &http.Client{Transport: proxiedTransport, Timeout: 30 * time.Second}
+
+// The busy/free state exported for external tools like status bars
+type status struct {
+	Busy      bool   `json:"busy"`
+	Current   string `json:"current,omitempty"`
+	Until     string `json:"until,omitempty"`
+	Next      string `json:"next,omitempty"`
+	NextStart string `json:"nextStart,omitempty"`
+	Text      string `json:"text"`
+}
+
+// Writes the current busy/free state and the next meeting to the file in the status-file preference, if any. The file
+// has the status in JSON if its extension is .json or a single line of text otherwise
+func exportStatus(events []event) {
+	path := dailyApp.Preferences().String("status-file")
+	if path == "" || shuttingDown.Load() {
+		return
+	}
+
+	current := createStatus(events)
+	content := []byte(current.Text + "\n")
+	if strings.HasSuffix(strings.ToLower(path), ".json") {
+		var err error
+		content, err = json.Marshal(current)
+		if err != nil {
+			slog.Error("Could not encode status", "error", err)
+			return
+		}
+	}
+
+	// the file is replaced in one go so that readers never see it half written
+	temporaryPath := path + ".tmp"
+	err := os.WriteFile(temporaryPath, content, 0644)
+	if err == nil {
+		err = os.Rename(temporaryPath, path)
+	}
+	if err != nil {
+		slog.Error("Could not write status file "+path, "error", err)
+	}
+}
+
+// What is posted to the status webhook when a meeting starts or, with only the state, when it ends
+type webhookPayload struct {
+	State    string `json:"state"`
+	Title    string `json:"title,omitempty"`
+	Start    string `json:"start,omitempty"`
+	End      string `json:"end,omitempty"`
+	JoinLink string `json:"joinLink,omitempty"`
+}
+
+// Posts to the URL in the status-webhook-url preference when a meeting starts and when the user is no longer in one,
+// for tools like "on air" lights or dashboards. A failed post is retried on the next call
+func updateStatusWebhook(events []event) {
+	webhookUrl := dailyApp.Preferences().String("status-webhook-url")
+	if webhookUrl == "" || shuttingDown.Load() {
+		return
+	}
+
+	postedEvent := dailyApp.Preferences().String("status-webhook-event")
+	meeting := findCurrentMeeting(events, false)
+	var payload webhookPayload
+	switch {
+	case meeting != nil && meeting.id != postedEvent:
+		payload = webhookPayload{State: "meeting", Title: meeting.title, Start: meeting.start.Format(time.RFC3339), End: meeting.end.Format(time.RFC3339)}
+		if meeting.isVirtualMeeting() {
+			payload.JoinLink = meeting.location
+		}
+	case meeting == nil && postedEvent != "":
+		payload = webhookPayload{State: "clear"}
+	default:
+		return
+	}
+
+	err := postWebhook(webhookUrl, payload)
+	if err != nil {
+		slog.Error("Could not post the status to the webhook", "error", err)
+		return
+	}
+	if meeting != nil {
+		dailyApp.Preferences().SetString("status-webhook-event", meeting.id)
+	} else {
+		dailyApp.Preferences().RemoveValue("status-webhook-event")
+	}
+}
+
+func postWebhook(webhookUrl string, payload webhookPayload) error {
+	slog.Debug("Posting " + payload.State + " status to the webhook")
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+	response, err := webhookClient.Post(webhookUrl, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		return fmt.Errorf("Webhook responded %s", response.Status)
+	}
+
+	return nil
+}
+
+// Gets the state at the current time. Events that don't make the user busy, like declined ones or those shown as free,
+// aren't considered current or next meetings
+func createStatus(events []event) status {
+	var current, next *event
+	for pos := range events {
+		event := &events[pos]
+		if !event.isBusy() || event.isFinished() {
+			continue
+		}
+		if event.isStarted() {
+			if current == nil {
+				current = event
+			}
+		} else if event.start.After(now()) && (next == nil || event.start.Before(next.start)) {
+			next = event
+		}
+	}
+
+	result := status{Text: message("status-free")}
+	if next != nil {
+		result.Next = next.title
+		result.NextStart = next.start.Format(time.RFC3339)
+		result.Text = message("status-next") + next.title + " (" + next.start.Format("3:04PM") + ")"
+	}
+	if current != nil {
+		result.Busy = true
+		result.Current = current.title
+		result.Until = current.end.Format(time.RFC3339)
+		result.Text = message("status-busy") + current.title + " (" + message("until") + current.end.Format("3:04PM") + ")"
+	}
+
+	return result
+}
+
+// Gets the ongoing meeting that makes the user busy, if any. In-person events don't if only virtual meetings are
+// wanted
+func findCurrentMeeting(events []event, virtualOnly bool) *event {
+	for pos := range events {
+		event := &events[pos]
+		if event.isStarted() && !event.isFinished() && event.isBusy() && (!virtualOnly || event.isVirtualMeeting()) {
+			return event
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/test"
+)
+
+func TestUpdateStatusWebhook(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var posted []webhookPayload
+	failing := false
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		if failing {
+			writer.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		var payload webhookPayload
+		_ = json.NewDecoder(request.Body).Decode(&payload)
+		posted = append(posted, payload)
+	}))
+	defer server.Close()
+	dailyApp.Preferences().SetString("status-webhook-url", server.URL)
+
+	start := now().Add(-10 * time.Minute)
+	meeting := event{id: "standup", title: "Standup", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234"}
+	updateStatusWebhook([]event{meeting})
+	updateStatusWebhook([]event{meeting})
+	if len(posted) != 1 {
+		t.Fatalf("Actual %d posts don't match expected 1 for the same meeting", len(posted))
+	}
+	expected := webhookPayload{State: "meeting", Title: "Standup", Start: meeting.start.Format(time.RFC3339), End: meeting.end.Format(time.RFC3339), JoinLink: meeting.location}
+	if posted[0] != expected {
+		t.Errorf("Actual payload %+v doesn't match expected %+v", posted[0], expected)
+	}
+
+	failing = true
+	updateStatusWebhook(nil)
+	failing = false
+	updateStatusWebhook(nil)
+	updateStatusWebhook(nil)
+	if len(posted) != 2 || posted[1] != (webhookPayload{State: "clear"}) {
+		t.Errorf("Actual posts %+v don't match expected a single clear after the failure", posted)
+	}
+}
+
+func TestUpdateStatusWebhookIgnoresBlockedAndFreeEvents(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	var posted []webhookPayload
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		var payload webhookPayload
+		_ = json.NewDecoder(request.Body).Decode(&payload)
+		posted = append(posted, payload)
+	}))
+	defer server.Close()
+	dailyApp.Preferences().SetString("status-webhook-url", server.URL)
+	dailyApp.Preferences().SetStringList("title-blocklist", []string{"^Lunch$"})
+
+	start := now().Add(-10 * time.Minute)
+	events := []event{
+		{id: "lunch", title: "Lunch", start: start, end: start.Add(time.Hour)},
+		{id: "focus", title: "Focus time", start: start, end: start.Add(time.Hour), free: true},
+	}
+	updateStatusWebhook(dimBlockedEvents(events))
+	if len(posted) != 0 {
+		t.Errorf("Actual posts %+v don't match expected none for blocked and free events", posted)
+	}
+}
+
+type freeEventsBusyTest struct {
+	preference bool
+	expectedId string
+}
+
+func TestFindCurrentMeetingShownAsFree(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+
+	start := now().Add(-10 * time.Minute)
+	events := []event{
+		{id: "focus", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234", free: true},
+		{id: "standup", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/5678"},
+	}
+	var freeEventsBusyTests = []freeEventsBusyTest{
+		{false, "standup"},
+		{true, "focus"},
+	}
+	for i, test := range freeEventsBusyTests {
+		dailyApp.Preferences().SetBool("free-events-busy", test.preference)
+		if actual := findCurrentMeeting(events, true); actual == nil || actual.id != test.expectedId {
+			t.Errorf("%d. Actual meeting %v doesn't match expected %s", i, actual, test.expectedId)
+		}
+	}
+}
+
+type currentMeetingTest struct {
+	virtualOnly bool
+	expectedId  string
+}
+
+func TestFindCurrentMeeting(t *testing.T) {
+	start := now().Add(-10 * time.Minute)
+	events := []event{
+		{id: "lunch", start: start, end: start.Add(time.Hour), location: "Cafeteria"},
+		{id: "standup", start: start, end: start.Add(time.Hour), location: "https://zoom.us/j/1234"},
+	}
+	var currentMeetingTests = []currentMeetingTest{
+		{true, "standup"},
+		{false, "lunch"},
+	}
+	for i, test := range currentMeetingTests {
+		if actual := findCurrentMeeting(events, test.virtualOnly); actual == nil || actual.id != test.expectedId {
+			t.Errorf("%d. Actual meeting %v doesn't match expected %s", i, actual, test.expectedId)
+		}
+	}
+}
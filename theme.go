@@ -0,0 +1,84 @@
+package main
+
+import (
+	"fmt"
+	"image/color"
+	"log/slog"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+)
+
+// A theme that replaces the primary color of the default one with the accent color picked by the user
+type accentTheme struct {
+	fyne.Theme
+	accent color.Color
+}
+
+func (accent *accentTheme) Color(name fyne.ThemeColorName, variant fyne.ThemeVariant) color.Color {
+	switch name {
+	case theme.ColorNamePrimary, theme.ColorNameHyperlink:
+		return accent.accent
+	case theme.ColorNameForegroundOnPrimary:
+		return contrastColor(accent.accent)
+	case theme.ColorNameFocus:
+		return withAlpha(accent.accent, 0x7f)
+	case theme.ColorNameSelection:
+		return withAlpha(accent.accent, 0x3f)
+	}
+
+	return accent.Theme.Color(name, variant)
+}
+
+// Applies the accent color stored in the preferences, going back to the default theme if there isn't one
+func applyAccentColor() {
+	hex := dailyApp.Preferences().String("accent-color")
+	if hex == "" {
+		dailyApp.Settings().SetTheme(theme.DefaultTheme())
+		return
+	}
+
+	accent, err := parseHexColor(hex)
+	if err != nil {
+		slog.Warn("Invalid accent color. Using the default theme", "color", hex, "error", err)
+		dailyApp.Settings().SetTheme(theme.DefaultTheme())
+		return
+	}
+
+	slog.Debug("Applying accent color", "color", hex)
+	dailyApp.Settings().SetTheme(&accentTheme{Theme: theme.DefaultTheme(), accent: accent})
+}
+
+// Parses a color in the #rrggbb format
+func parseHexColor(hex string) (color.NRGBA, error) {
+	result := color.NRGBA{A: 0xff}
+	_, err := fmt.Sscanf(hex, "#%02x%02x%02x", &result.R, &result.G, &result.B)
+	if err != nil || len(hex) != 7 {
+		return color.NRGBA{}, fmt.Errorf("color %q is not in the #rrggbb format", hex)
+	}
+
+	return result, nil
+}
+
+func formatHexColor(c color.Color) string {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	return fmt.Sprintf("#%02x%02x%02x", nrgba.R, nrgba.G, nrgba.B)
+}
+
+// Gets black or white, whichever reads better on top of the color
+func contrastColor(c color.Color) color.Color {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	// perceived brightness as per the W3C accessibility guidelines
+	brightness := (299*int(nrgba.R) + 587*int(nrgba.G) + 114*int(nrgba.B)) / 1000
+	if brightness > 150 {
+		return color.Black
+	}
+
+	return color.White
+}
+
+func withAlpha(c color.Color, alpha uint8) color.Color {
+	nrgba := color.NRGBAModel.Convert(c).(color.NRGBA)
+	nrgba.A = alpha
+	return nrgba
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"time"
+
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+)
+
+// Whether the whole week of the displayed day is shown instead of only the day
+func isWeekView() bool {
+	return dailyApp.Preferences().Bool("week-view")
+}
+
+// Switches between showing the displayed day and its week
+func toggleWeekView(weekButton *widget.Button) {
+	dailyApp.Preferences().SetBool("week-view", !isWeekView())
+	updateWeekButton(weekButton)
+	if dayLabel != nil {
+		dayLabel.SetText(displayedPeriod())
+	}
+	updateNavigationButtons()
+	refresh(false)
+}
+
+// Shows the view that the button switches to
+func updateWeekButton(weekButton *widget.Button) {
+	if isWeekView() {
+		weekButton.SetIcon(theme.ListIcon())
+	} else {
+		weekButton.SetIcon(theme.GridIcon())
+	}
+}
+
+// Gets the Monday that starts the week of the day
+func startOfWeek(day time.Time) time.Time {
+	start := startOfDay(day)
+	return start.AddDate(0, 0, -(int(start.Weekday())+6)%7)
+}
+
+// Gets the days that the previous and next buttons move
+func navigationStep() int {
+	if isWeekView() {
+		return 7
+	}
+	return 1
+}
+
+// Gets the text of the day label, which is the week of the displayed day in week view
+func displayedPeriod() string {
+	if isWeekView() {
+		return message("week-of") + startOfWeek(displayDay).Format(dayFormat)
+	}
+	return displayDay.Format(dayFormat)
+}
+
+// Whether events that start at the time are shown, being on the displayed day or, in week view, its week
+func isDisplayed(start time.Time) bool {
+	if isWeekView() {
+		weekStart := startOfWeek(displayDay)
+		return !start.Before(weekStart) && start.Before(weekStart.AddDate(0, 0, 7))
+	}
+	return isOnSameDay(displayDay, start)
+}
+
+// Creates the header of the events of a day in week view. Today's is highlighted
+func createDayHeader(day time.Time) *widget.Label {
+	result := widget.NewLabelWithStyle(day.Format(dayFormat), fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+	if isOnSameDay(day, now()) {
+		result.Importance = widget.HighImportance
+	}
+
+	return result
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/test"
+	"fyne.io/fyne/v2/widget"
+	"github.com/theHilikus/daily/internal/ui"
+)
+
+type startOfWeekTest struct {
+	day      time.Time
+	expected time.Time
+}
+
+func TestStartOfWeek(t *testing.T) {
+	monday := time.Date(2024, 11, 18, 0, 0, 0, 0, time.UTC)
+	var startOfWeekTests = []startOfWeekTest{
+		{monday, monday},
+		{time.Date(2024, 11, 20, 15, 30, 0, 0, time.UTC), monday},
+		{time.Date(2024, 11, 24, 23, 59, 0, 0, time.UTC), monday},
+		{time.Date(2024, 11, 25, 8, 0, 0, 0, time.UTC), monday.AddDate(0, 0, 7)},
+	}
+
+	for i, test := range startOfWeekTests {
+		if actual := startOfWeek(test.day); !actual.Equal(test.expected) {
+			t.Errorf("%d. Actual %v doesn't match expected %v", i, actual, test.expected)
+		}
+	}
+}
+
+func TestIsDisplayed(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	displayDay = time.Date(2024, 11, 20, 12, 0, 0, 0, time.UTC)
+	defer func() { displayDay = time.Time{} }()
+
+	sunday := time.Date(2024, 11, 24, 9, 0, 0, 0, time.UTC)
+	if isDisplayed(sunday) {
+		t.Errorf("Event of another day is displayed in day view")
+	}
+	dailyApp.Preferences().SetBool("week-view", true)
+	if !isDisplayed(sunday) || isDisplayed(sunday.AddDate(0, 0, 1)) {
+		t.Errorf("Actual displayed events don't match the week in week view")
+	}
+	if actual := navigationStep(); actual != 7 {
+		t.Errorf("Actual step %d doesn't match expected a week", actual)
+	}
+}
+
+func TestRenderEventsWeekView(t *testing.T) {
+	dailyApp = test.NewApp()
+	defer dailyApp.Quit()
+	mainWindow = dailyApp.NewWindow("test")
+	defer func() { mainWindow = nil }()
+	eventsList = container.NewVBox()
+	renderedEvents = map[string]*ui.Event{}
+	dailyApp.Preferences().SetBool("week-view", true)
+
+	today := startOfDay(now()).Add(9 * time.Hour)
+	tomorrow := today.AddDate(0, 0, 1)
+	renderEvents([]event{
+		{id: "standup", title: "standup", start: today, end: today.Add(15 * time.Minute)},
+		{id: "review", title: "review", start: today.Add(time.Hour), end: today.Add(2 * time.Hour)},
+		{id: "planning", title: "planning", start: tomorrow, end: tomorrow.Add(time.Hour)},
+	})
+
+	if len(eventsList.Objects) != 5 {
+		t.Fatalf("Actual %d rows don't match expected 3 events and 2 day headers", len(eventsList.Objects))
+	}
+	todayHeader, isHeader := eventsList.Objects[0].(*widget.Label)
+	if !isHeader || todayHeader.Text != today.Format(dayFormat) || todayHeader.Importance != widget.HighImportance {
+		t.Errorf("Actual first row %v is not the highlighted header of today", eventsList.Objects[0])
+	}
+	tomorrowHeader, isHeader := eventsList.Objects[3].(*widget.Label)
+	if !isHeader || tomorrowHeader.Text != tomorrow.Format(dayFormat) || tomorrowHeader.Importance == widget.HighImportance {
+		t.Errorf("Actual fourth row %v is not the header of tomorrow", eventsList.Objects[3])
+	}
+}